@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/parser"
+)
+
+// newConfigCmd agrupa los subcomandos que inspeccionan la configuración.
+func newConfigCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect tfsuit configuration",
+	}
+	c.AddCommand(newConfigPresetsCmd())
+	c.AddCommand(newConfigValidateCmd())
+	c.AddCommand(newConfigPrintCmd())
+	return c
+}
+
+func newConfigValidateCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "validate [path]",
+		Short: "Check the config (and nested configs) used to scan path",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := "."
+			if len(args) == 1 {
+				target = args[0]
+			}
+			cmd.SilenceUsage = true // los problemas ya se listaron
+			return runConfigValidate(cmd.OutOrStdout(), target)
+		},
+	}
+	c.Flags().StringVarP(&cfgFile, "config", "c", "", "configuration file (HCL or JSON); discovered from the target up to the git root when empty")
+	return c
+}
+
+func newConfigPrintCmd() *cobra.Command {
+	var printFormat string
+	c := &cobra.Command{
+		Use:   "print [path]",
+		Short: "Print the fully resolved config used to scan path",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := "."
+			if len(args) == 1 {
+				target = args[0]
+			}
+			cfg, err := loadConfig(target)
+			if err != nil {
+				return err
+			}
+			// config efectiva del target, con los tfsuit.hcl anidados aplicados
+			dir := target
+			if info, err := os.Stat(target); err == nil && !info.IsDir() {
+				dir = filepath.Dir(target)
+			}
+			if cfg, err = config.NewTree(target, cfg).For(dir); err != nil {
+				return err
+			}
+			resolved := cfg.Resolved()
+			out := cmd.OutOrStdout()
+			switch printFormat {
+			case "hcl":
+				fmt.Fprintf(out, "# resolved from %s\n", cfg.Path())
+				_, err = out.Write(resolved.HCL())
+			case "json":
+				var b []byte
+				if b, err = resolved.JSON(); err == nil {
+					_, err = out.Write(b)
+				}
+			default:
+				err = fmt.Errorf("unknown format %q (hcl or json)", printFormat)
+			}
+			return err
+		},
+	}
+	c.Flags().StringVarP(&cfgFile, "config", "c", "", "configuration file (HCL or JSON); discovered from the target up to the git root when empty")
+	c.Flags().StringVarP(&printFormat, "format", "f", "hcl", "output format: hcl|json")
+	return c
+}
+
+// runConfigValidate checks the config file scan would use and every nested config under target,
+// each on its own so an issue is reported once against the file that declares it, with the
+// labels and file names of the subtree it applies to. Then the merged configs are loaded like
+// scan does.
+func runConfigValidate(out io.Writer, target string) error {
+	path, err := configPath(target)
+	if err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return errors.New("invalid config")
+	}
+	files, err := parser.Discover(target)
+	if err != nil {
+		return err
+	}
+
+	// nombres por archivo de config, de los .tf a los que se aplica
+	tree := config.NewLayerTree(target, path)
+	names := map[string]map[string][]string{}
+	var layers []string
+	for _, dir := range append([]string{target}, dirsOf(files)...) {
+		for _, layer := range tree.Layers(dir) {
+			if names[layer] == nil {
+				names[layer] = map[string][]string{}
+				layers = append(layers, layer)
+			}
+		}
+	}
+	for _, tf := range files {
+		byKind := map[string][]string{"file": {filepath.Base(tf)}}
+		forEachLabel(tf, func(kind, name string) {
+			byKind[kind] = append(byKind[kind], name)
+		})
+		forEachInstanceKey(tf, func(key string) {
+			byKind["instance_key"] = append(byKind["instance_key"], key)
+		})
+		for _, layer := range tree.Layers(filepath.Dir(tf)) {
+			for kind, list := range byKind {
+				names[layer][kind] = append(names[layer][kind], list...)
+			}
+		}
+	}
+
+	errs, warns := 0, 0
+	report := func(file string, issue config.Issue) {
+		fmt.Fprintf(out, "%s: %s\n", displayPath(file), issue)
+		if issue.Warning {
+			warns++
+		} else {
+			errs++
+		}
+	}
+	for _, layer := range layers {
+		for _, issue := range config.ValidateFile(layer, names[layer]) {
+			report(layer, issue)
+		}
+	}
+
+	// config efectiva: lo que solo falla al combinar las capas, como un bloque requerido ausente
+	if errs == 0 {
+		cfg, err := config.Load(path)
+		if err != nil {
+			for _, msg := range strings.Split(err.Error(), "\n") {
+				report(path, config.Issue{Message: msg})
+			}
+		} else {
+			tree = config.NewTree(target, cfg)
+			for _, dir := range dirsOf(files) {
+				if _, err := tree.For(dir); err != nil {
+					fmt.Fprintf(out, "error: %v\n", err)
+					errs++
+					break
+				}
+			}
+		}
+	}
+
+	if errs > 0 {
+		return fmt.Errorf("%d errors, %d warnings", errs, warns)
+	}
+	if warns > 0 {
+		fmt.Fprintf(out, "⚠️  %s is usable, with %d warnings\n", displayPath(path), warns)
+		return nil
+	}
+	fmt.Fprintf(out, "✅ %s is valid\n", displayPath(path))
+	return nil
+}
+
+// dirsOf returns the directories of files, sorted and without duplicates.
+func dirsOf(files []string) []string {
+	seen := map[string]bool{}
+	var dirs []string
+	for _, f := range files {
+		if dir := filepath.Dir(f); !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// displayPath shows config paths relative to the working directory when they are below it.
+func displayPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return abs
+	}
+	if rel, err := filepath.Rel(wd, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return rel
+	}
+	return abs
+}
+
+func newConfigPresetsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "presets [name]",
+		Short: "List built-in presets, or print the rules of one",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				return printPreset(cmd.OutOrStdout(), args[0])
+			}
+			return listPresets(cmd.OutOrStdout())
+		},
+	}
+}
+
+func listPresets(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, p := range config.Presets() {
+		fmt.Fprintf(w, "%s\t%s\n", p.Name, p.Description)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "\nUse one with `preset = \"<name>\"` in tfsuit.hcl; blocks you add on top override it.")
+	return nil
+}
+
+func printPreset(out io.Writer, name string) error {
+	p, ok := config.LookupPreset(name)
+	if !ok {
+		return fmt.Errorf("unknown preset %q (see tfsuit config presets)", name)
+	}
+	fmt.Fprintf(out, "# %s: %s\n%s\n", p.Name, p.Description, strings.TrimSpace(p.Source))
+	return nil
+}
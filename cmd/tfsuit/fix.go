@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -11,9 +12,14 @@ import (
 )
 
 var (
-	write    bool
-	dryRun   bool
-	fixTypes string
+	write        bool
+	dryRun       bool
+	fixTypes     string
+	interactive  bool
+	renameMap    string
+	moved        bool
+	undo         bool
+	removeUnused bool
 )
 
 func newFixCmd() *cobra.Command {
@@ -27,6 +33,10 @@ func newFixCmd() *cobra.Command {
 				target = args[0]
 			}
 
+			if undo {
+				return rewrite.Undo(target)
+			}
+
 			// Si --write se pasó, forzamos dryRun=false
 			if write {
 				dryRun = false
@@ -40,19 +50,42 @@ func newFixCmd() *cobra.Command {
 				return err
 			}
 
-			cfg, err := config.Load(cfgFile)
+			cfg, err := loadConfig(target)
 			if err != nil {
 				return err
 			}
-			opts := rewrite.Options{Write: write, DryRun: dryRun, FixKinds: allowedKinds}
+			var renames *config.RenameMap
+			if renameMap != "" {
+				if _, err := os.Stat(renameMap); err != nil {
+					return err
+				}
+				if renames, err = config.LoadRenameMap(renameMap); err != nil {
+					return err
+				}
+			}
+			opts := rewrite.Options{
+				Write:        write,
+				DryRun:       dryRun,
+				FixKinds:     allowedKinds,
+				Interactive:  interactive,
+				Input:        cmd.InOrStdin(),
+				RenameMap:    renames,
+				Moved:        moved,
+				RemoveUnused: removeUnused,
+			}
 			return rewrite.Run(target, cfg, opts)
 		},
 	}
 
 	cmd.Flags().BoolVar(&write, "write", false, "write changes in-place")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "preview diff only (default true when --write is not supplied)")
-	cmd.Flags().StringVarP(&cfgFile, "config", "c", "tfsuit.hcl", "configuration file (HCL or JSON)")
-	cmd.Flags().StringVar(&fixTypes, "fix-types", "", "comma-separated kinds to fix (file,variable,output,module,data,resource)")
+	cmd.Flags().StringVarP(&cfgFile, "config", "c", "", "configuration file (HCL or JSON); discovered from the target up to the git root when empty")
+	cmd.Flags().StringVar(&fixTypes, "fix-types", "", "comma-separated kinds to fix (file,variable,output,module,data,resource,check,spacing,tags,layout,ordering)")
+	cmd.Flags().StringVar(&renameMap, "rename-map", "", "HCL file mapping addresses to new labels (renames = { \"aws_instance.web\" = \"web_primary\" })")
+	cmd.Flags().BoolVar(&moved, "moved", false, "add moved {} blocks for renamed resources and modules")
+	cmd.Flags().BoolVar(&removeUnused, "remove-unused", false, "delete the variables, locals and data sources reported by the unused block")
+	cmd.Flags().BoolVar(&undo, "undo", false, "revert the last --write run using its journal ("+rewrite.JournalFile+")")
+	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "confirm, skip or rename every planned change (choices are kept in .tfsuit-renames.hcl)")
 	return cmd
 }
 
@@ -67,7 +100,11 @@ func parseFixTypesFlag(flag string) (map[string]bool, error) {
 		"module":   {},
 		"data":     {},
 		"resource": {},
+		"check":    {},
 		"spacing":  {},
+		"tags":     {},
+		"layout":   {},
+		"ordering": {},
 	}
 	kinds := map[string]bool{}
 	for _, part := range strings.Split(flag, ",") {
@@ -76,7 +113,7 @@ func parseFixTypesFlag(flag string) (map[string]bool, error) {
 			continue
 		}
 		if _, ok := valid[part]; !ok {
-			return nil, fmt.Errorf("unknown fix type %q (valid: file,variable,output,module,data,resource,spacing)", part)
+			return nil, fmt.Errorf("unknown fix type %q (valid: file,variable,output,module,data,resource,check,spacing,tags,layout,ordering)", part)
 		}
 		kinds[part] = true
 	}
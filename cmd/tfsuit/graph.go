@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/josdagaro/tfsuit/internal/rewrite"
+)
+
+var (
+	graphFormat    string
+	graphResources bool
+)
+
+func newGraphCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "graph [path]",
+		Short: "Export the module, provider and reference graph",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := "."
+			if len(args) == 1 {
+				target = args[0]
+			}
+			cfg, err := loadConfig(target)
+			if err != nil {
+				return err
+			}
+			g, err := rewrite.BuildGraph(target, cfg, rewrite.GraphOptions{Resources: graphResources})
+			if err != nil {
+				return err
+			}
+			out, err := g.Format(graphFormat)
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(cmd.OutOrStdout(), out)
+			return nil
+		},
+	}
+
+	c.Flags().StringVarP(&cfgFile, "config", "c", "", "configuration file (HCL or JSON); discovered from the target up to the git root when empty")
+	c.Flags().StringVarP(&graphFormat, "format", "f", "dot", "output format: dot|mermaid|json")
+	c.Flags().BoolVar(&graphResources, "resources", false, "include resource and data source nodes")
+
+	return c
+}
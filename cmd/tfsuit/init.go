@@ -53,37 +53,7 @@ func runInit(root string) error {
 	// Parse labels
 	cts := &counts{namesByKind: map[string][]string{}}
 	for _, path := range files {
-		src, err := os.ReadFile(path)
-		if err != nil {
-			continue
-		}
-		file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
-		if diags.HasErrors() {
-			continue
-		}
-		body := file.Body.(*hclsyntax.Body)
-		for _, b := range body.Blocks {
-			switch b.Type {
-			case "variable", "output", "module":
-				if len(b.Labels) == 0 {
-					continue
-				}
-				name := b.Labels[0]
-				cts.bump(b.Type, name)
-			case "resource":
-				if len(b.Labels) < 2 {
-					continue
-				}
-				name := b.Labels[1]
-				cts.bump("resource", name)
-			case "data":
-				if len(b.Labels) < 2 {
-					continue
-				}
-				name := b.Labels[1]
-				cts.bump("data", name)
-			}
-		}
+		forEachLabel(path, cts.bump)
 	}
 
 	// Interactive Q&A
@@ -157,6 +127,53 @@ func runInit(root string) error {
 	return nil
 }
 
+// forEachLabel calls fn with the kind and name label of every named block in path.
+func forEachLabel(path string, fn func(kind, name string)) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return
+	}
+	body := file.Body.(*hclsyntax.Body)
+	for _, b := range body.Blocks {
+		switch b.Type {
+		case "variable", "output", "module", "check":
+			if len(b.Labels) == 0 {
+				continue
+			}
+			fn(b.Type, b.Labels[0])
+		case "resource", "data":
+			if len(b.Labels) < 2 {
+				continue
+			}
+			fn(b.Type, b.Labels[1])
+		}
+	}
+}
+
+// forEachInstanceKey calls fn with every literal for_each key of the resources and modules in path.
+func forEachInstanceKey(path string, fn func(key string)) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return
+	}
+	for _, b := range file.Body.(*hclsyntax.Body).Blocks {
+		if b.Type != "resource" && b.Type != "module" {
+			continue
+		}
+		for _, k := range parser.InstanceKeys(b) {
+			fn(k.Key)
+		}
+	}
+}
+
 func (c *counts) bump(kind, name string) {
 	c.namesByKind[kind] = append(c.namesByKind[kind], name)
 	for _, ch := range name {
@@ -19,8 +19,27 @@ var (
 	fail    bool
 )
 
+// configPath returns --config, or the outermost tfsuit.hcl, .tfsuit.hcl or tfsuit.json between
+// target and the git root.
+func configPath(target string) (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	return config.Discover(target)
+}
+
+// loadConfig loads the config at configPath; the nested ones are layered per directory by
+// config.Tree.
+func loadConfig(target string) (*config.Config, error) {
+	path, err := configPath(target)
+	if err != nil {
+		return nil, err
+	}
+	return config.Load(path)
+}
+
 func runScan(target string) error {
-	cfg, err := config.Load(cfgFile)
+	cfg, err := loadConfig(target)
 	if err != nil {
 		return err
 	}
@@ -58,14 +77,16 @@ func rootCmd() *cobra.Command {
 	cmd.SetVersionTemplate("{{.Version}}\n")
 
 	// flags compartidos
-	cmd.Flags().StringVarP(&cfgFile, "config", "c", "tfsuit.hcl", "configuration file (HCL or JSON)")
-	cmd.Flags().StringVarP(&format, "format", "f", "pretty", "output format: pretty|json|sarif")
+	cmd.Flags().StringVarP(&cfgFile, "config", "c", "", "configuration file (HCL or JSON); discovered from the target up to the git root when empty")
+	cmd.Flags().StringVarP(&format, "format", "f", "pretty", "output format: pretty|json|json-v2|sarif")
 	cmd.Flags().BoolVar(&fail, "fail", false, "return non-zero exit if violations found")
 
 	// subcomandos
 	cmd.AddCommand(newScanCmd())
 	cmd.AddCommand(newFixCmd())
 	cmd.AddCommand(newInitCmd())
+	cmd.AddCommand(newConfigCmd())
+	cmd.AddCommand(newGraphCmd())
 
 	return cmd
 }
@@ -8,6 +8,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/engine"
 )
 
 func repoPath(parts ...string) string {
@@ -15,21 +18,111 @@ func repoPath(parts ...string) string {
 	return filepath.Join(all...)
 }
 
+// copySimple copies samples/simple into a temp dir, so the scan cache is not written into the repo.
+func copySimple(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	src := repoPath("samples", "simple")
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		t.Fatalf("read sample: %v", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			t.Fatalf("read sample: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), b, 0o644); err != nil {
+			t.Fatalf("write sample: %v", err)
+		}
+	}
+	return dir
+}
+
 func TestRunScanSuccessAndFailFlag(t *testing.T) {
-	cfgFile = repoPath("samples", "simple", "tfsuit.hcl")
+	dir := copySimple(t)
+	cfgFile = filepath.Join(dir, "tfsuit.hcl")
 	format = "json"
 	fail = false
-	if err := runScan(repoPath("samples", "simple")); err != nil {
+	if err := runScan(dir); err != nil {
 		t.Fatalf("runScan success: %v", err)
 	}
 
 	fail = true
-	if err := runScan(repoPath("samples", "simple")); err == nil {
+	if err := runScan(dir); err == nil {
 		t.Fatalf("expected error when --fail flag enabled")
 	}
 	fail = false
 }
 
+func TestLoadConfigDiscovers(t *testing.T) {
+	cfgFile = ""
+	target := repoPath("samples", "simple")
+	cfg, err := loadConfig(target)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	// la base es la config más externa; las anidadas se aplican por directorio
+	if want, _ := filepath.Abs(repoPath("tfsuit.hcl")); cfg.Path() != want {
+		t.Fatalf("discovered %s, want %s", cfg.Path(), want)
+	}
+	eff, err := config.NewTree(target, cfg).For(target)
+	if err != nil {
+		t.Fatalf("For: %v", err)
+	}
+	if want, _ := filepath.Abs(repoPath("samples", "simple", "tfsuit.hcl")); eff.Path() != want {
+		t.Fatalf("effective config %s, want %s", eff.Path(), want)
+	}
+}
+
+func TestScanLayersConfigsAboveTarget(t *testing.T) {
+	repo := t.TempDir()
+	net := filepath.Join(repo, "legacy", "net")
+	if err := os.MkdirAll(net, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir .git: %v", err)
+	}
+	files := map[string]string{
+		"tfsuit.hcl": `
+variables { pattern = "^[a-z_]+$" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`,
+		filepath.Join("legacy", "tfsuit.hcl"): `variables { ignore_exact = ["LegacyName"] }`,
+		filepath.Join("legacy", "net", "main.tf"): `variable "LegacyName" {}
+
+variable "Other" {}
+`,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	cfgFile = ""
+	cfg, err := loadConfig(net)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	findings, stats, err := engine.Scan(net, cfg)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if len(stats.Layers) != 2 || stats.Layers[1] != filepath.Join(repo, "legacy", "tfsuit.hcl") {
+		t.Fatalf("scan should report both config layers, got %v", stats.Layers)
+	}
+	if len(findings) != 1 || findings[0].Name != "Other" {
+		t.Fatalf("legacy/tfsuit.hcl should be layered over the root config, got %+v", findings)
+	}
+}
+
 func TestFixCommandRespectsConfigFlag(t *testing.T) {
 	dir := t.TempDir()
 	cfgPath := filepath.Join(dir, "tfsuit.hcl")
@@ -143,13 +236,14 @@ func TestParseFixTypesFlag(t *testing.T) {
 }
 
 func TestRootScanCommand(t *testing.T) {
+	dir := copySimple(t)
 	cmd := rootCmd()
 	cmd.SetOut(io.Discard)
 	cmd.SetErr(io.Discard)
 	cmd.SetArgs([]string{
 		"scan",
-		repoPath("samples", "simple"),
-		"-c", repoPath("samples", "simple", "tfsuit.hcl"),
+		dir,
+		"-c", filepath.Join(dir, "tfsuit.hcl"),
 		"-f", "json",
 	})
 	if err := cmd.Execute(); err != nil {
@@ -191,3 +285,127 @@ func TestInitHelpersCoverages(t *testing.T) {
 		t.Fatalf("askYesNo should use default when empty")
 	}
 }
+
+func TestConfigPresetsCommand(t *testing.T) {
+	out := &bytes.Buffer{}
+	cmd := newConfigCmd()
+	cmd.SetArgs([]string{"presets"})
+	cmd.SetOut(out)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("config presets: %v", err)
+	}
+	if !strings.Contains(out.String(), "snake-case-strict") {
+		t.Fatalf("preset list missing entries: %s", out.String())
+	}
+
+	out.Reset()
+	cmd.SetArgs([]string{"presets", "kebab-case-modules"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("config presets <name>: %v", err)
+	}
+	if !strings.Contains(out.String(), "modules   { pattern") {
+		t.Fatalf("preset rules not printed: %s", out.String())
+	}
+}
+
+func TestConfigValidateAndPrint(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tfsuit.hcl"), []byte(`
+preset = "snake-case"
+block_spacing { allow_compact = ["outputs"] }
+`), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	cfgFile = ""
+
+	out := &bytes.Buffer{}
+	cmd := newConfigCmd()
+	cmd.SetArgs([]string{"validate", dir})
+	cmd.SetOut(out)
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil || !strings.Contains(out.String(), `unknown kind "outputs"`) {
+		t.Fatalf("expected unknown kind error, got %v: %s", err, out.String())
+	}
+
+	out.Reset()
+	cmd.SetArgs([]string{"print", dir, "--format", "json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("config print: %v", err)
+	}
+	if !strings.Contains(out.String(), `"require_provider": true`) || strings.Contains(out.String(), "preset") {
+		t.Fatalf("print should show resolved defaults: %s", out.String())
+	}
+}
+
+func TestConfigValidateReportsEachFileOnce(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(repo, "legacy"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		"tfsuit.hcl": `
+variables {
+  pattern      = ".*"
+  ignore_exact = ["Nope"]
+}
+outputs {
+  pattern      = ".*"
+  ignore_regex = ["("]
+}
+modules   { pattern = ".*" }
+resources { pattern = "[a-" }
+`,
+		filepath.Join("legacy", "tfsuit.hcl"): `variables { ignore_exact = ["Legacy", "Gone"] }`,
+		filepath.Join("legacy", "main.tf"):    `variable "Legacy" {}`,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	cfgFile = ""
+
+	var out bytes.Buffer
+	if err := runConfigValidate(&out, repo); err == nil || !strings.HasPrefix(err.Error(), "2 errors, 2 warnings") {
+		t.Fatalf("expected 2 errors and 2 warnings, got %v:\n%s", err, out.String())
+	}
+	root, legacy := filepath.Join(repo, "tfsuit.hcl")+": ", filepath.Join(repo, "legacy", "tfsuit.hcl")+": "
+	for _, want := range []string{
+		root + `warning: variables.ignore_exact "Nope" matches nothing in the tree`,
+		root + "error: outputs: invalid ignore_regex '('",
+		root + "error: resources: invalid pattern '[a-'",
+		legacy + `warning: variables.ignore_exact "Gone" matches nothing in the tree`,
+	} {
+		if strings.Count(out.String(), want) != 1 {
+			t.Fatalf("expected %q once:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestGraphCommand(t *testing.T) {
+	dir := copySimple(t)
+	var out bytes.Buffer
+	cmd := newGraphCmd()
+	cmd.SetArgs([]string{dir, "-c", filepath.Join(dir, "tfsuit.hcl"), "-f", "mermaid"})
+	cmd.SetOut(&out)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("graph command execute: %v", err)
+	}
+	for _, want := range []string{"flowchart LR", `[["module.Alb-Bad"]]`, "class "} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("graph output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	cmd = newGraphCmd()
+	cmd.SetArgs([]string{dir, "-f", "svg"})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	if err := cmd.Execute(); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+	graphFormat = "dot"
+}
@@ -17,8 +17,8 @@ func newScanCmd() *cobra.Command {
 	}
 
 	// mismos flags que el comando raíz
-	c.Flags().StringVarP(&cfgFile, "config", "c", "tfsuit.hcl", "configuration file (HCL or JSON)")
-	c.Flags().StringVarP(&format, "format", "f", "pretty", "output format: pretty|json|sarif")
+	c.Flags().StringVarP(&cfgFile, "config", "c", "", "configuration file (HCL or JSON); discovered from the target up to the git root when empty")
+	c.Flags().StringVarP(&format, "format", "f", "pretty", "output format: pretty|json|json-v2|sarif")
 	c.Flags().BoolVar(&fail, "fail", false, "return non-zero exit if violations found")
 
 	return c
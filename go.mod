@@ -6,6 +6,7 @@ require (
 	github.com/hashicorp/hcl/v2 v2.23.0
 	github.com/sergi/go-diff v1.4.0
 	github.com/spf13/cobra v1.9.1
+	github.com/zclconf/go-cty v1.13.0
 )
 
 require (
@@ -16,7 +17,6 @@ require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
-	github.com/zclconf/go-cty v1.13.0 // indirect
 	golang.org/x/mod v0.8.0 // indirect
 	golang.org/x/sys v0.5.0 // indirect
 	golang.org/x/text v0.11.0 // indirect
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+)
+
+// AttributeRule checks the string value of a resource attribute, such as bucket of
+// aws_s3_bucket or tags["Name"] of every type ("*"), with the same settings as a naming rule.
+type AttributeRule struct {
+	Type      string   `hcl:"type,label" json:"type"`
+	Attribute string   `hcl:"attribute,label" json:"attribute"`
+	Body      hcl.Body `hcl:",remain" json:"-"`
+	Rule
+
+	path []string
+}
+
+// attributePathRe accepts name, tags.Name and tags["Name"].
+var attributePathRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)(?:\.([A-Za-z_][A-Za-z0-9_-]*)|\["([^"]+)"\])?$`)
+
+// Path is the attribute name followed by the object key, if any: ["tags", "Name"].
+func (a *AttributeRule) Path() []string {
+	return a.path
+}
+
+func (a *AttributeRule) key() string {
+	return a.Type + " " + a.Attribute
+}
+
+// decodeAttributeBodies fills the rule of HCL attribute blocks, which gohcl leaves in Body.
+func (c *Config) decodeAttributeBodies() error {
+	for _, a := range c.Attributes {
+		if a.Body == nil {
+			continue
+		}
+		if diags := gohcl.DecodeBody(a.Body, nil, &a.Rule); diags.HasErrors() {
+			return fmt.Errorf("%s", diags.Error())
+		}
+		a.Body = nil
+	}
+	return nil
+}
+
+func (a *AttributeRule) compile() error {
+	m := attributePathRe.FindStringSubmatch(a.Attribute)
+	if m == nil {
+		return fmt.Errorf("attribute %q %q: expected name, name.key or name[\"key\"]", a.Type, a.Attribute)
+	}
+	a.path = []string{m[1]}
+	if key := m[2] + m[3]; key != "" {
+		a.path = append(a.path, key)
+	}
+	if a.Pattern == "" && a.Template == "" {
+		return fmt.Errorf("attribute %q %q: pattern or template is required", a.Type, a.Attribute)
+	}
+	if err := a.Rule.compile(); err != nil {
+		return fmt.Errorf("attribute %q %q: %w", a.Type, a.Attribute, err)
+	}
+	return nil
+}
+
+// AttributeRules returns the rules that apply to resources of type typ, exact types first.
+func (c *Config) AttributeRules(typ string) []*AttributeRule {
+	var exact, wildcard []*AttributeRule
+	for _, a := range c.Attributes {
+		switch a.Type {
+		case typ:
+			exact = append(exact, a)
+		case "*":
+			wildcard = append(wildcard, a)
+		}
+	}
+	return append(exact, wildcard...)
+}
+
+// mergeAttributes keeps inherited attribute rules and merges those redefined for the same type
+// and attribute like any other rule.
+func mergeAttributes(base, over []*AttributeRule) []*AttributeRule {
+	var out []*AttributeRule
+	index := map[string]int{}
+	for _, list := range [][]*AttributeRule{base, over} {
+		for _, a := range list {
+			if i, ok := index[a.key()]; ok {
+				prev := out[i].Rule
+				out[i] = &AttributeRule{Type: a.Type, Attribute: a.Attribute, Rule: *mergeRule(&prev, &a.Rule)}
+				continue
+			}
+			index[a.key()] = len(out)
+			out = append(out, &AttributeRule{Type: a.Type, Attribute: a.Attribute, Rule: *mergeRule(nil, &a.Rule)})
+		}
+	}
+	return out
+}
+
+// Subject names the checked value in findings: "bucket of aws_s3_bucket.logs".
+func (a *AttributeRule) Subject(typ, name string) string {
+	return fmt.Sprintf("%s of %s.%s", a.Attribute, typ, name)
+}
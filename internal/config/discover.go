@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileNames are the config file names looked up in a directory, in order of preference.
+var FileNames = []string{"tfsuit.hcl", ".tfsuit.hcl", "tfsuit.json"}
+
+// findIn returns the preferred config file in dir, if any.
+func findIn(dir string) (string, bool) {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// Discover returns the outermost config file found from target (a directory, or a file inside
+// one) up to the enclosing git root; the configs below it are layered on top per directory by
+// Tree. Outside a git repository there is no root to stop at, so it returns the nearest config
+// instead of picking up a stray one in a home or system directory.
+func Discover(target string) (string, error) {
+	dir, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	start, nearest, outermost := dir, "", ""
+	for {
+		if path, ok := findIn(dir); ok {
+			if nearest == "" {
+				nearest = path
+			}
+			outermost = path
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return foundOr(outermost, start, dir)
+		}
+		up := filepath.Dir(dir)
+		if up == dir {
+			break
+		}
+		dir = up
+	}
+	return foundOr(nearest, start, dir)
+}
+
+func foundOr(path, start, stop string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("no config found: looked for %s from %s up to %s (use --config or tfsuit init)",
+			strings.Join(FileNames, ", "), start, stop)
+	}
+	return path, nil
+}
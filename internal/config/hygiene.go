@@ -0,0 +1,52 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// DescriptionRequired reports whether require_description is on.
+func (r *Rule) DescriptionRequired() bool {
+	return r.RequireDescription != nil && *r.RequireDescription
+}
+
+// TypeRequired reports whether require_type is on (variables only).
+func (r *Rule) TypeRequired() bool {
+	return r.RequireType != nil && *r.RequireType
+}
+
+// ForEachPreferred reports whether prefer_for_each is on (instance_keys only).
+func (r *Rule) ForEachPreferred() bool {
+	return r != nil && r.PreferForEach != nil && *r.PreferForEach
+}
+
+// SensitiveRequired reports whether name matches require_sensitive_for.
+func (r *Rule) SensitiveRequired(name string) bool {
+	return r.sensitiveRe != nil && r.sensitiveRe.MatchString(name)
+}
+
+// DescriptionOK checks a literal description against description_pattern.
+func (r *Rule) DescriptionOK(desc string) bool {
+	return r.descriptionRe == nil || r.descriptionRe.MatchString(desc)
+}
+
+func (r *Rule) compileHygiene() error {
+	var errs []error
+	r.sensitiveRe, r.descriptionRe = nil, nil
+	if r.RequireSensitiveFor != "" {
+		re, err := regexp.Compile(r.RequireSensitiveFor)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid require_sensitive_for '%s': %w", r.RequireSensitiveFor, err))
+		}
+		r.sensitiveRe = re
+	}
+	if r.DescriptionPattern != "" {
+		re, err := regexp.Compile(r.DescriptionPattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid description_pattern '%s': %w", r.DescriptionPattern, err))
+		}
+		r.descriptionRe = re
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,364 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// loadLayered decodes path and the files it extends, without compiling. The preset goes first,
+// extended files are applied in order and the file itself goes last, so it wins.
+func loadLayered(path string, stack []string) (*Config, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, seen := range stack {
+		if seen == abs {
+			return nil, fmt.Errorf("extends cycle: %s", strings.Join(append(stack, abs), " -> "))
+		}
+	}
+	stack = append(stack, abs)
+
+	own, err := decodeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	merged := &Config{}
+	if own.Preset != "" {
+		preset, err := presetLayer(own.Preset)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		merged.merge(preset)
+	}
+	for _, ext := range own.Extends {
+		if !filepath.IsAbs(ext) {
+			ext = filepath.Join(filepath.Dir(path), ext)
+		}
+		base, err := loadLayered(ext, stack)
+		if err != nil {
+			return nil, fmt.Errorf("%s: extends: %w", path, err)
+		}
+		merged.merge(base)
+	}
+	merged.merge(own)
+	return merged, nil
+}
+
+// merge layers over on top of c:
+//   - pattern, template, require_provider, max_length, min_length, disallow_type_stutter, the
+//     variable/output hygiene settings, prefer_for_each,
+//     block_spacing.enabled/min_blank_lines/max_blank_lines, block_spacing.nested.min_blank_lines
+//     and fix.on_collision replace the inherited value when set;
+//   - template segments are replaced by name, attribute rules merged by type and attribute;
+//   - ignore_exact, ignore_regex, forbidden_words, block_spacing.allow_compact,
+//     block_spacing.nested.blocks and ordering.sort are unioned, ordering.meta_arguments
+//     replaced when set;
+//   - renames and the tags values/from_variables maps are merged key by key, the other tags
+//     settings like the rules above;
+//   - provider_assignment.assign blocks of over are tried before the inherited ones;
+//   - layout file lists replace the inherited list of the same kind.
+//
+// Only exported fields are copied, so the result must be compiled again.
+func (c *Config) merge(over *Config) {
+	c.Variables = mergeRule(c.Variables, over.Variables)
+	c.Outputs = mergeRule(c.Outputs, over.Outputs)
+	c.Modules = mergeRule(c.Modules, over.Modules)
+	c.Resources = mergeRule(c.Resources, over.Resources)
+	c.Data = mergeRule(c.Data, over.Data)
+	c.Checks = mergeRule(c.Checks, over.Checks)
+	c.InstanceKeys = mergeRule(c.InstanceKeys, over.InstanceKeys)
+	c.Files = mergeRule(c.Files, over.Files)
+	c.Attributes = mergeAttributes(c.Attributes, over.Attributes)
+
+	if over.Spacing != nil {
+		bs := c.Spacing.copy()
+		if over.Spacing.Enabled != nil {
+			bs.Enabled = over.Spacing.Enabled
+		}
+		if over.Spacing.MinBlankLines > 0 {
+			bs.MinBlankLines = over.Spacing.MinBlankLines
+		}
+		if over.Spacing.MaxBlankLines > 0 {
+			bs.MaxBlankLines = over.Spacing.MaxBlankLines
+		}
+		bs.AllowCompact = union(bs.AllowCompact, over.Spacing.AllowCompact)
+		if n := over.Spacing.Nested; n != nil {
+			if bs.Nested == nil {
+				bs.Nested = &NestedSpacing{}
+			}
+			if n.MinBlankLines > 0 {
+				bs.Nested.MinBlankLines = n.MinBlankLines
+			}
+			bs.Nested.Blocks = union(bs.Nested.Blocks, n.Blocks)
+		}
+		c.Spacing = bs
+	} else if c.Spacing != nil {
+		c.Spacing = c.Spacing.copy()
+	}
+
+	if over.Fix != nil {
+		fix := &FixSettings{}
+		if c.Fix != nil {
+			*fix = *c.Fix
+		}
+		if over.Fix.OnCollision != "" {
+			fix.OnCollision = over.Fix.OnCollision
+		}
+		c.Fix = fix
+	}
+
+	c.Tags = mergeTags(c.Tags, over.Tags)
+	c.Unused = mergeUnused(c.Unused, over.Unused)
+	c.ProviderAssignment = mergeProviderAssignment(c.ProviderAssignment, over.ProviderAssignment)
+	c.ProviderChain = mergeProviderChain(c.ProviderChain, over.ProviderChain)
+	c.ModuleSources = mergeModuleSources(c.ModuleSources, over.ModuleSources)
+	c.Layout = mergeLayout(c.Layout, over.Layout)
+	c.Ordering = mergeOrdering(c.Ordering, over.Ordering)
+	c.Renames = mergeMap(c.Renames, over.Renames)
+}
+
+// copy returns the exported settings of bs, or an empty block_spacing for nil.
+func (bs *BlockSpacing) copy() *BlockSpacing {
+	out := &BlockSpacing{}
+	if bs == nil {
+		return out
+	}
+	out.Enabled = bs.Enabled
+	out.MinBlankLines = bs.MinBlankLines
+	out.MaxBlankLines = bs.MaxBlankLines
+	out.AllowCompact = bs.AllowCompact
+	if bs.Nested != nil {
+		out.Nested = &NestedSpacing{MinBlankLines: bs.Nested.MinBlankLines, Blocks: bs.Nested.Blocks}
+	}
+	return out
+}
+
+func mergeRule(base, over *Rule) *Rule {
+	if base == nil && over == nil {
+		return nil
+	}
+	r := &Rule{}
+	if base != nil {
+		r.Pattern = base.Pattern
+		r.IgnoreExact = base.IgnoreExact
+		r.IgnoreRegex = base.IgnoreRegex
+		r.RequireProvider = base.RequireProvider
+		r.Template = base.Template
+		r.MaxLength = base.MaxLength
+		r.MinLength = base.MinLength
+		r.ForbiddenWords = base.ForbiddenWords
+		r.DisallowTypeStutter = base.DisallowTypeStutter
+		r.RequireDescription = base.RequireDescription
+		r.RequireType = base.RequireType
+		r.RequireSensitiveFor = base.RequireSensitiveFor
+		r.DescriptionPattern = base.DescriptionPattern
+		r.PreferForEach = base.PreferForEach
+	}
+	if over != nil {
+		if over.Pattern != "" {
+			r.Pattern = over.Pattern
+		}
+		r.IgnoreExact = union(r.IgnoreExact, over.IgnoreExact)
+		r.IgnoreRegex = union(r.IgnoreRegex, over.IgnoreRegex)
+		if over.RequireProvider != nil {
+			r.RequireProvider = over.RequireProvider
+		}
+		if over.Template != "" {
+			r.Template = over.Template
+		}
+		if over.MaxLength > 0 {
+			r.MaxLength = over.MaxLength
+		}
+		if over.MinLength > 0 {
+			r.MinLength = over.MinLength
+		}
+		r.ForbiddenWords = union(r.ForbiddenWords, over.ForbiddenWords)
+		if over.DisallowTypeStutter != nil {
+			r.DisallowTypeStutter = over.DisallowTypeStutter
+		}
+		if over.RequireDescription != nil {
+			r.RequireDescription = over.RequireDescription
+		}
+		if over.RequireType != nil {
+			r.RequireType = over.RequireType
+		}
+		if over.RequireSensitiveFor != "" {
+			r.RequireSensitiveFor = over.RequireSensitiveFor
+		}
+		if over.DescriptionPattern != "" {
+			r.DescriptionPattern = over.DescriptionPattern
+		}
+		if over.PreferForEach != nil {
+			r.PreferForEach = over.PreferForEach
+		}
+	}
+	r.Segments = mergeSegments(base, over)
+	return r
+}
+
+// mergeSegments keeps inherited segments and replaces those redefined by name.
+func mergeSegments(base, over *Rule) []*Segment {
+	var out []*Segment
+	index := map[string]int{}
+	for _, rule := range []*Rule{base, over} {
+		if rule == nil {
+			continue
+		}
+		for _, seg := range rule.Segments {
+			cp := &Segment{Name: seg.Name, Values: seg.Values, Pattern: seg.Pattern, Default: seg.Default}
+			if i, ok := index[seg.Name]; ok {
+				out[i] = cp
+				continue
+			}
+			index[seg.Name] = len(out)
+			out = append(out, cp)
+		}
+	}
+	return out
+}
+
+func union(a, b []string) []string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	seen := map[string]struct{}{}
+	out := make([]string, 0, len(a)+len(b))
+	for _, list := range [][]string{a, b} {
+		for _, v := range list {
+			if _, ok := seen[v]; ok {
+				continue
+			}
+			seen[v] = struct{}{}
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Tree resolves the effective config of every directory below a root: the base config layered
+// with each nested config file (see FileNames) found between the root and the directory,
+// nearest last, much like .editorconfig. It is not safe for concurrent use.
+type Tree struct {
+	root  string
+	base  *Config
+	byDir map[string]*Config
+}
+
+// NewTree returns the resolver for the directories below target, with base as the outermost
+// config. When target lies inside base's directory the tree is rooted there, so the configs
+// between base and target are layered too; otherwise it is rooted at target.
+func NewTree(target string, base *Config) *Tree {
+	root, _ := filepath.Abs(target)
+	if base.path != "" {
+		if dir, err := filepath.Abs(filepath.Dir(base.path)); err == nil && within(dir, root) {
+			root = dir
+		}
+	}
+	return &Tree{root: root, base: base, byDir: map[string]*Config{}}
+}
+
+// NewLayerTree returns a Tree rooted like NewTree for the config file at basePath, without
+// loading it. It is only meant for Layers, when the configs may not load (config validate).
+func NewLayerTree(target, basePath string) *Tree {
+	return NewTree(target, &Config{path: basePath})
+}
+
+// For returns the effective config of dir, which must be root or below it.
+func (t *Tree) For(dir string) (*Config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if cfg, ok := t.byDir[dir]; ok {
+		return cfg, nil
+	}
+	parent := t.base
+	if dir != t.root {
+		up := filepath.Dir(dir)
+		if up == dir || !within(t.root, dir) {
+			return t.base, nil
+		}
+		if parent, err = t.For(up); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg := parent
+	if nested, ok := findIn(dir); ok && !t.isBase(nested) {
+		layer, err := loadLayered(nested, nil)
+		if err != nil {
+			return nil, err
+		}
+		eff := &Config{path: nested}
+		eff.merge(parent)
+		eff.merge(layer)
+		if err := eff.compileRules(); err != nil {
+			return nil, fmt.Errorf("%s: %w", nested, err)
+		}
+		cfg = eff
+	}
+	t.byDir[dir] = cfg
+	return cfg, nil
+}
+
+// Layers returns the config files that make up the effective config of dir, outermost first:
+// base's file and the nested ones between root and dir, each preceded by the files it extends.
+func (t *Tree) Layers(dir string) []string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	var nested []string
+	for ; within(t.root, dir); dir = filepath.Dir(dir) {
+		if path, ok := findIn(dir); ok && !t.isBase(path) {
+			nested = append([]string{path}, nested...)
+		}
+		if dir == t.root || filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	seen := map[string]bool{}
+	var out []string
+	if t.base.path != "" {
+		out = extendChain(t.base.path, seen)
+	}
+	for _, path := range nested {
+		out = append(out, extendChain(path, seen)...)
+	}
+	return out
+}
+
+// extendChain returns path after the files it extends, recursively, skipping the ones in seen.
+func extendChain(path string, seen map[string]bool) []string {
+	abs, err := filepath.Abs(path)
+	if err != nil || seen[abs] {
+		return nil
+	}
+	seen[abs] = true
+	var out []string
+	if own, err := decodeFile(abs); err == nil {
+		for _, ext := range own.Extends {
+			if !filepath.IsAbs(ext) {
+				ext = filepath.Join(filepath.Dir(abs), ext)
+			}
+			out = append(out, extendChain(ext, seen)...)
+		}
+	}
+	return append(out, abs)
+}
+
+func (t *Tree) isBase(path string) bool {
+	if t.base.path == "" {
+		return false
+	}
+	a, err1 := filepath.Abs(path)
+	b, err2 := filepath.Abs(t.base.path)
+	return err1 == nil && err2 == nil && a == b
+}
+
+func within(root, dir string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Layout maps block kinds to the file names (globs such as vars_*.tf) they may be declared in.
+// Kinds left unset may live anywhere. fix moves misplaced blocks to the first pattern that is a
+// plain file name.
+type Layout struct {
+	Variable  []string `hcl:"variable,optional" json:"variable,omitempty"`
+	Output    []string `hcl:"output,optional" json:"output,omitempty"`
+	Locals    []string `hcl:"locals,optional" json:"locals,omitempty"`
+	Provider  []string `hcl:"provider,optional" json:"provider,omitempty"`
+	Terraform []string `hcl:"terraform,optional" json:"terraform,omitempty"`
+	Module    []string `hcl:"module,optional" json:"module,omitempty"`
+	Resource  []string `hcl:"resource,optional" json:"resource,omitempty"`
+	Data      []string `hcl:"data,optional" json:"data,omitempty"`
+}
+
+type layoutKind struct {
+	kind  string
+	files *[]string
+}
+
+func (l *Layout) kinds() []layoutKind {
+	return []layoutKind{
+		{"variable", &l.Variable},
+		{"output", &l.Output},
+		{"locals", &l.Locals},
+		{"provider", &l.Provider},
+		{"terraform", &l.Terraform},
+		{"module", &l.Module},
+		{"resource", &l.Resource},
+		{"data", &l.Data},
+	}
+}
+
+func (l *Layout) init() error {
+	if l == nil {
+		return nil
+	}
+	for _, k := range l.kinds() {
+		for _, glob := range *k.files {
+			if _, err := path.Match(glob, ""); err != nil {
+				return fmt.Errorf("invalid layout.%s glob '%s': %w", k.kind, glob, err)
+			}
+			if strings.ContainsAny(glob, `/\`) {
+				return fmt.Errorf("invalid layout.%s glob '%s': file names only", k.kind, glob)
+			}
+		}
+	}
+	return nil
+}
+
+// Files returns the allowed file name globs for blocks of kind (the block type), if any.
+func (l *Layout) Files(kind string) []string {
+	if l == nil {
+		return nil
+	}
+	for _, k := range l.kinds() {
+		if k.kind == kind {
+			return *k.files
+		}
+	}
+	return nil
+}
+
+// Allows reports whether a block of kind may be declared in a file named name.
+func (l *Layout) Allows(kind, name string) bool {
+	globs := l.Files(kind)
+	return len(globs) == 0 || matchGlobs(globs, name)
+}
+
+// Target is the file fix moves misplaced blocks of kind to: the first allowed name without
+// wildcards, or "" when there is none.
+func (l *Layout) Target(kind string) string {
+	for _, glob := range l.Files(kind) {
+		if !strings.ContainsAny(glob, `*?[`) {
+			return glob
+		}
+	}
+	return ""
+}
+
+func mergeLayout(base, over *Layout) *Layout {
+	if base == nil && over == nil {
+		return nil
+	}
+	l := &Layout{}
+	for _, p := range []*Layout{base, over} {
+		if p == nil {
+			continue
+		}
+		src := p.kinds()
+		for i, k := range l.kinds() {
+			if len(*src[i].files) > 0 {
+				*k.files = *src[i].files
+			}
+		}
+	}
+	return l
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var wordRe = regexp.MustCompile(`[A-Za-z0-9]+`)
+
+// Violations lists what name breaks besides the pattern and template: length limits, forbidden
+// words and, for resources and data sources of type typ, repeating the type. One message each.
+func (r *Rule) Violations(kind, typ, name string) []string {
+	var out []string
+	if n := utf8.RuneCountInString(name); r.MaxLength > 0 && n > r.MaxLength {
+		out = append(out, fmt.Sprintf("%s '%s' is %d characters long, max_length is %d", kind, name, n, r.MaxLength))
+	} else if r.MinLength > 0 && n < r.MinLength {
+		out = append(out, fmt.Sprintf("%s '%s' is %d characters long, min_length is %d", kind, name, n, r.MinLength))
+	}
+	if w, ok := r.ForbiddenWord(name); ok {
+		out = append(out, fmt.Sprintf("%s '%s' contains forbidden word '%s'", kind, name, w))
+	}
+	if r.StutterDisallowed() {
+		if part := TypeStutter(typ, name); part != "" {
+			out = append(out, fmt.Sprintf("%s '%s' repeats its type %s ('%s')", kind, name, typ, part))
+		}
+	}
+	return out
+}
+
+// StutterDisallowed reports whether disallow_type_stutter is on.
+func (r *Rule) StutterDisallowed() bool {
+	return r.DisallowTypeStutter != nil && *r.DisallowTypeStutter
+}
+
+// ForbiddenWord returns the first entry of forbidden_words that appears in name as whole words,
+// ignoring case: "temp" is found in temp_bucket and logs-Temp, but not in template.
+func (r *Rule) ForbiddenWord(name string) (string, bool) {
+	words := lowerWords(name)
+	for _, fw := range r.ForbiddenWords {
+		if seq := lowerWords(fw); len(seq) > 0 && indexWords(words, seq) >= 0 {
+			return fw, true
+		}
+	}
+	return "", false
+}
+
+// TypeStutter returns the part of name that repeats the resource type typ, provider prefix
+// aside: the longest run of two or more of the type's words found in name ("s3_bucket" in
+// s3_bucket_logs for aws_s3_bucket). A single shared word is not stutter (role_admin for
+// aws_iam_role) unless it is the whole name and the whole type (aws_instance.instance).
+// It is empty when there is none.
+func TypeStutter(typ, name string) string {
+	start, end := stutterSpan(typ, name)
+	if start < 0 {
+		return ""
+	}
+	spans := wordRe.FindAllStringIndex(name, -1)
+	return name[spans[start][0]:spans[end-1][1]]
+}
+
+// StripStutter removes the part of name that repeats typ, with its separator. A name made only
+// of the type becomes "this".
+func StripStutter(typ, name string) string {
+	start, end := stutterSpan(typ, name)
+	if start < 0 {
+		return name
+	}
+	spans := wordRe.FindAllStringIndex(name, -1)
+	if start == 0 && end == len(spans) {
+		return "this"
+	}
+	var from, to int
+	if start == 0 {
+		from, to = spans[start][0], spans[end][0] // drop the separator after the run
+	} else {
+		from, to = spans[start-1][1], spans[end-1][1] // drop the separator before it
+	}
+	return name[:from] + name[to:]
+}
+
+// stutterSpan returns the word range [start, end) of name repeating typ, or -1.
+func stutterSpan(typ, name string) (int, int) {
+	tw := lowerWords(typ)
+	if len(tw) < 2 {
+		return -1, -1
+	}
+	tw = tw[1:] // provider prefix
+	words := lowerWords(name)
+	if len(tw) == 1 && len(words) == 1 && words[0] == tw[0] {
+		return 0, 1
+	}
+	for size := len(tw); size >= 2; size-- {
+		for i := 0; i+size <= len(tw); i++ {
+			if at := indexWords(words, tw[i:i+size]); at >= 0 {
+				return at, at + size
+			}
+		}
+	}
+	return -1, -1
+}
+
+func lowerWords(s string) []string {
+	words := wordRe.FindAllString(s, -1)
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	return words
+}
+
+func indexWords(words, seq []string) int {
+	for i := 0; i+len(seq) <= len(words); i++ {
+		match := true
+		for j, w := range seq {
+			if words[i+j] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
@@ -14,54 +15,176 @@ import (
 )
 
 type Rule struct {
-	Pattern         string   `hcl:"pattern" json:"pattern"`
-	IgnoreExact     []string `hcl:"ignore_exact,optional" json:"ignore_exact"`
-	IgnoreRegex     []string `hcl:"ignore_regex,optional" json:"ignore_regex"`
+	Pattern         string   `hcl:"pattern,optional" json:"pattern"`
+	IgnoreExact     []string `hcl:"ignore_exact,optional" json:"ignore_exact,omitempty"`
+	IgnoreRegex     []string `hcl:"ignore_regex,optional" json:"ignore_regex,omitempty"`
 	RequireProvider *bool    `hcl:"require_provider,optional" json:"require_provider,omitempty"`
 
-	patternRe    *regexp.Regexp
-	ignoreReList []*regexp.Regexp
-	requireProv  bool
+	// Template describes names as {segments} and literals, e.g. "{env}_{service}_{purpose}";
+	// each segment is constrained by the segment block of the same name.
+	Template string     `hcl:"template,optional" json:"template,omitempty"`
+	Segments []*Segment `hcl:"segment,block" json:"segments,omitempty"`
+
+	MaxLength      int      `hcl:"max_length,optional" json:"max_length,omitempty"`
+	MinLength      int      `hcl:"min_length,optional" json:"min_length,omitempty"`
+	ForbiddenWords []string `hcl:"forbidden_words,optional" json:"forbidden_words,omitempty"`
+	// DisallowTypeStutter rejects resource and data labels that repeat their type (aws_s3_bucket.s3_bucket_logs).
+	DisallowTypeStutter *bool `hcl:"disallow_type_stutter,optional" json:"disallow_type_stutter,omitempty"`
+
+	// Hygiene of variables and outputs; require_type applies to variables only.
+	RequireDescription  *bool  `hcl:"require_description,optional" json:"require_description,omitempty"`
+	RequireType         *bool  `hcl:"require_type,optional" json:"require_type,omitempty"`
+	RequireSensitiveFor string `hcl:"require_sensitive_for,optional" json:"require_sensitive_for,omitempty"`
+	DescriptionPattern  string `hcl:"description_pattern,optional" json:"description_pattern,omitempty"`
+
+	// PreferForEach (instance_keys only) reports count = length(...), whose instance keys shift
+	// when the list changes.
+	PreferForEach *bool `hcl:"prefer_for_each,optional" json:"prefer_for_each,omitempty"`
+
+	patternRe     *regexp.Regexp
+	ignoreReList  []*regexp.Regexp
+	requireProv   bool
+	templateParts []templatePart
+	templateRe    *regexp.Regexp
+	sensitiveRe   *regexp.Regexp
+	descriptionRe *regexp.Regexp
 }
 
 type Config struct {
-	Variables Rule          `hcl:"variables,block" json:"variables"`
-	Outputs   Rule          `hcl:"outputs,block" json:"outputs"`
-	Modules   Rule          `hcl:"modules,block" json:"modules"`
-	Resources Rule          `hcl:"resources,block" json:"resources"`
+	// Preset names a built-in rule set layered below everything else in this file.
+	Preset string `hcl:"preset,optional" json:"preset,omitempty"`
+	// Extends lists config files (relative to this one) layered below it, in order.
+	Extends []string `hcl:"extends,optional" json:"extends,omitempty"`
+
+	Variables *Rule         `hcl:"variables,block" json:"variables"`
+	Outputs   *Rule         `hcl:"outputs,block" json:"outputs"`
+	Modules   *Rule         `hcl:"modules,block" json:"modules"`
+	Resources *Rule         `hcl:"resources,block" json:"resources"`
 	Data      *Rule         `hcl:"data,block" json:"data,omitempty"`
+	Checks    *Rule         `hcl:"checks,block" json:"checks,omitempty"`
 	Files     *Rule         `hcl:"files,block" json:"files,omitempty"`
 	Spacing   *BlockSpacing `hcl:"block_spacing,block" json:"block_spacing,omitempty"`
+	Fix       *FixSettings  `hcl:"fix,block" json:"fix,omitempty"`
+
+	// Attributes check attribute values (bucket, tags["Name"], …) of resources per type.
+	Attributes []*AttributeRule `hcl:"attribute,block" json:"attributes,omitempty"`
+	// Tags is the required tags policy; nil disables it.
+	Tags *TagsPolicy `hcl:"tags,block" json:"tags,omitempty"`
+	// Unused reports variables, locals and data sources nothing references.
+	Unused *UnusedCheck `hcl:"unused,block" json:"unused,omitempty"`
+	// ProviderAssignment picks the alias fix injects into blocks that must set a provider.
+	ProviderAssignment *ProviderAssignment `hcl:"provider_assignment,block" json:"provider_assignment,omitempty"`
+	// ProviderChain reports provider mappings and references that don't line up across module calls.
+	ProviderChain *ProviderChain `hcl:"provider_chain,block" json:"provider_chain,omitempty"`
+	// ModuleSources locates registry and git modules (vendor directory).
+	ModuleSources *ModuleSources `hcl:"module_sources,block" json:"module_sources,omitempty"`
+	// Layout restricts the files each block kind may be declared in.
+	Layout *Layout `hcl:"layout,block" json:"layout,omitempty"`
+	// Ordering sorts blocks by label and places meta-arguments.
+	Ordering *Ordering `hcl:"ordering,block" json:"ordering,omitempty"`
+	// InstanceKeys checks the keys of literal for_each maps and sets; nil disables it.
+	InstanceKeys *Rule `hcl:"instance_keys,block" json:"instance_keys,omitempty"`
+
+	// Renames maps Terraform addresses (optionally "dir:" prefixed) to new labels for `tfsuit fix`.
+	Renames map[string]string `hcl:"renames,optional" json:"renames,omitempty"`
+
+	path string
+}
+
+// Path is the file the config was loaded from; for nested configs, the nearest one.
+func (c *Config) Path() string {
+	return c.path
 }
 
 type BlockSpacing struct {
 	Enabled       *bool    `hcl:"enabled,optional" json:"enabled,omitempty"`
 	MinBlankLines int      `hcl:"min_blank_lines,optional" json:"min_blank_lines,omitempty"`
 	AllowCompact  []string `hcl:"allow_compact,optional" json:"allow_compact,omitempty"`
+	// MaxBlankLines caps the blank lines between blocks and between the items of a block; 0 means no cap.
+	MaxBlankLines int `hcl:"max_blank_lines,optional" json:"max_blank_lines,omitempty"`
+	// Nested requires blank lines around nested blocks (lifecycle, dynamic, …) inside other blocks.
+	Nested *NestedSpacing `hcl:"nested,block" json:"nested,omitempty"`
 
 	enabled  bool
 	allowSet map[string]struct{}
 }
 
+// NestedSpacing separates nested blocks from the attributes and blocks next to them.
+type NestedSpacing struct {
+	MinBlankLines int `hcl:"min_blank_lines,optional" json:"min_blank_lines,omitempty"`
+	// Blocks lists the nested block types the rule applies to; all of them when empty.
+	Blocks []string `hcl:"blocks,optional" json:"blocks,omitempty"`
+}
+
+// FixSettings tunes how `tfsuit fix` resolves conflicts it cannot decide on its own.
+type FixSettings struct {
+	// OnCollision decides what happens when a generated name is already taken in the module:
+	// "error" (default) aborts, "suffix" appends _1, _2, … and "skip" leaves the label untouched.
+	OnCollision string `hcl:"on_collision,optional" json:"on_collision,omitempty"`
+}
+
+// Collision strategies accepted by fix.on_collision.
+const (
+	CollisionError  = "error"
+	CollisionSuffix = "suffix"
+	CollisionSkip   = "skip"
+)
+
+func (f *FixSettings) init() error {
+	switch f.OnCollision {
+	case "":
+		f.OnCollision = CollisionError
+	case CollisionError, CollisionSuffix, CollisionSkip:
+	default:
+		return fmt.Errorf("invalid fix.on_collision '%s' (valid: error, suffix, skip)", f.OnCollision)
+	}
+	return nil
+}
+
 func (r *Rule) compile() error {
-	re, err := regexp.Compile(r.Pattern)
-	if err != nil {
-		return fmt.Errorf("invalid pattern '%s': %w", r.Pattern, err)
+	var errs []error
+	r.patternRe = nil
+	if r.Pattern != "" || r.Template == "" {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid pattern '%s': %w", r.Pattern, err))
+		}
+		r.patternRe = re
+	}
+	if r.Template != "" {
+		if err := r.compileTemplate(); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	r.patternRe = re
 
+	if err := r.compileHygiene(); err != nil {
+		errs = append(errs, err)
+	}
+
+	// con algún ignore_regex inválido la lista queda vacía, para no desalinearla de IgnoreRegex
+	var ignores []*regexp.Regexp
+	valid := true
 	for _, ig := range r.IgnoreRegex {
 		igr, err := regexp.Compile(ig)
 		if err != nil {
-			return fmt.Errorf("invalid ignore_regex '%s': %w", ig, err)
+			errs = append(errs, fmt.Errorf("invalid ignore_regex '%s': %w", ig, err))
+			valid = false
+			continue
 		}
-		r.ignoreReList = append(r.ignoreReList, igr)
+		ignores = append(ignores, igr)
 	}
-	return nil
+	r.ignoreReList = nil
+	if valid {
+		r.ignoreReList = ignores
+	}
+	return errors.Join(errs...)
 }
 
 func (r *Rule) Matches(name string) bool {
-	return r.patternRe.MatchString(name)
+	if r.patternRe != nil && !r.patternRe.MatchString(name) {
+		return false
+	}
+	return r.templateRe == nil || r.templateRe.MatchString(name)
 }
 
 func (r *Rule) IsIgnored(name string) bool {
@@ -96,6 +219,11 @@ func (c *Config) compileRules() error {
 	} else if c.Data.Pattern == "" {
 		c.Data.Pattern = ".*"
 	}
+	if c.Checks == nil {
+		c.Checks = &Rule{Pattern: ".*"}
+	} else if c.Checks.Pattern == "" {
+		c.Checks.Pattern = ".*"
+	}
 	if c.Files == nil {
 		c.Files = &Rule{Pattern: `.*\.tf$`}
 	} else if c.Files.Pattern == "" {
@@ -104,31 +232,76 @@ func (c *Config) compileRules() error {
 	if c.Spacing == nil {
 		c.Spacing = &BlockSpacing{}
 	}
+	if c.Fix == nil {
+		c.Fix = &FixSettings{}
+	}
 
 	type ruleDef struct {
+		name string
 		rule *Rule
 		def  bool
 	}
 
 	rules := []ruleDef{
-		{rule: &c.Variables, def: false},
-		{rule: &c.Outputs, def: false},
-		{rule: &c.Modules, def: true},
-		{rule: &c.Resources, def: false},
-		{rule: c.Data, def: false},
-		{rule: c.Files, def: false},
+		{name: "variables", rule: c.Variables, def: false},
+		{name: "outputs", rule: c.Outputs, def: false},
+		{name: "modules", rule: c.Modules, def: true},
+		{name: "resources", rule: c.Resources, def: false},
+		{name: "data", rule: c.Data, def: false},
+		{name: "checks", rule: c.Checks, def: false},
+		{name: "files", rule: c.Files, def: false},
 	}
 
+	var errs []error
 	for _, rd := range rules {
-		if err := rd.rule.compile(); err != nil {
-			return err
+		if rd.rule == nil {
+			errs = append(errs, fmt.Errorf("missing %s block", rd.name))
+			continue
+		}
+		if rd.rule.Pattern == "" && rd.rule.Template == "" {
+			errs = append(errs, fmt.Errorf("%s: pattern or template is required", rd.name))
 		}
 		rd.rule.setRequireProvider(rd.def)
 	}
-	if err := c.Spacing.init(); err != nil {
-		return err
+	if k := c.InstanceKeys; k != nil && k.Pattern == "" && k.Template == "" {
+		k.Pattern = ".*"
 	}
-	return nil
+	return errors.Join(append(errs, c.compileParts()...)...)
+}
+
+// compileParts compiles the rules and settings c declares, collecting every error instead of
+// stopping at the first one. Blocks left out are skipped, so it also works on a single layer.
+func (c *Config) compileParts() []error {
+	var errs []error
+	for _, nr := range c.namedRules() {
+		if nr.rule == nil {
+			continue
+		}
+		if err := nr.rule.compile(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", nr.block, err))
+		}
+	}
+	for _, a := range c.Attributes {
+		if err := a.compile(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.Spacing != nil {
+		if err := c.Spacing.init(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.Fix != nil {
+		if err := c.Fix.init(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, init := range []func() error{c.Tags.init, c.ProviderAssignment.init, c.Layout.init, c.Ordering.init} {
+		if err := init(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
 }
 
 func (bs *BlockSpacing) init() error {
@@ -140,6 +313,12 @@ func (bs *BlockSpacing) init() error {
 	} else {
 		bs.enabled = *bs.Enabled
 	}
+	if bs.MaxBlankLines > 0 && bs.MaxBlankLines < bs.MinBlankLines {
+		return fmt.Errorf("block_spacing.max_blank_lines (%d) is less than min_blank_lines (%d)", bs.MaxBlankLines, bs.MinBlankLines)
+	}
+	if bs.Nested != nil && bs.Nested.MinBlankLines <= 0 {
+		bs.Nested.MinBlankLines = 1
+	}
 	bs.allowSet = map[string]struct{}{}
 	for _, kind := range bs.AllowCompact {
 		kind = strings.ToLower(strings.TrimSpace(kind))
@@ -165,6 +344,26 @@ func (bs *BlockSpacing) MinLines() int {
 	return bs.MinBlankLines
 }
 
+// MaxLines is the most blank lines allowed between blocks or block items, 0 for no limit.
+func (bs *BlockSpacing) MaxLines() int {
+	if bs == nil {
+		return 0
+	}
+	return bs.MaxBlankLines
+}
+
+// NestedMin returns the blank lines required around nested blocks of type typ, if the nested
+// rule covers them.
+func (bs *BlockSpacing) NestedMin(typ string) (int, bool) {
+	if bs == nil || bs.Nested == nil {
+		return 0, false
+	}
+	if len(bs.Nested.Blocks) > 0 && !contains(bs.Nested.Blocks, typ) {
+		return 0, false
+	}
+	return bs.Nested.MinBlankLines, true
+}
+
 func (bs *BlockSpacing) AllowCompactKind(kind string) bool {
 	if bs == nil {
 		return false
@@ -173,19 +372,37 @@ func (bs *BlockSpacing) AllowCompactKind(kind string) bool {
 	return ok
 }
 
-// Load reads and parses a HCL or JSON config file.
+// Load reads and parses a HCL or JSON config file, layering the files it extends below it.
 func Load(path string) (*Config, error) {
+	cfg, err := loadLayered(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	cfg.path = path
+
+	if err := cfg.compileRules(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// decodeFile parses a single HCL or JSON config file as written, without defaults.
+func decodeFile(path string) (*Config, error) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	return decodeSource(path, data)
+}
 
+func decodeSource(path string, data []byte) (*Config, error) {
 	var cfg Config
 
 	switch filepath.Ext(path) {
 	case ".json":
 		if err := json.Unmarshal(data, &cfg); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%s: %w", path, err)
 		}
 	default: // assume HCL
 		file, diags := hclsyntax.ParseConfig(data, path, hcl.Pos{Line: 1, Column: 1})
@@ -195,11 +412,9 @@ func Load(path string) (*Config, error) {
 		if diags := gohcl.DecodeBody(file.Body, nil, &cfg); diags.HasErrors() {
 			return nil, fmt.Errorf("%s", diags.Error())
 		}
+		if err := cfg.decodeAttributeBodies(); err != nil {
+			return nil, err
+		}
 	}
-
-	if err := cfg.compileRules(); err != nil {
-		return nil, err
-	}
-
 	return &cfg, nil
 }
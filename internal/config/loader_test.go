@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -63,6 +65,7 @@ files {
 	}
 
 	assertDefaultRule(cfg.Data, "data")
+	assertDefaultRule(cfg.Checks, "checks")
 	if cfg.Files == nil || cfg.Files.Pattern != `^[a-z0-9_]+\.tf$` {
 		t.Fatalf("files pattern not loaded")
 	}
@@ -98,6 +101,35 @@ func TestLoadJSON(t *testing.T) {
 	}
 }
 
+func TestLoadJSONIgnoresUnknownFields(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempFile(t, dir, "tfsuit.json", `{
+  "variables": { "pattern": ".*", "patern": "^x$" },
+  "outputs":   { "pattern": ".*" },
+  "modules":   { "pattern": ".*" },
+  "resources": { "pattern": ".*" }
+}`)
+	if _, err := Load(path); err != nil {
+		t.Fatalf("Load json with extra keys: %v", err)
+	}
+	issues := ValidateFile(path, nil)
+	if len(issues) != 1 || !issues[0].Warning || !strings.Contains(issues[0].Message, "unknown field") {
+		t.Fatalf("expected one unknown field warning, got %v", issues)
+	}
+}
+
+func TestRuleRecompileKeepsIgnores(t *testing.T) {
+	r := Rule{Pattern: ".*", IgnoreRegex: []string{"^a", "^b"}}
+	for i := 0; i < 2; i++ {
+		if err := r.compile(); err != nil {
+			t.Fatalf("compile: %v", err)
+		}
+	}
+	if len(r.ignoreReList) != 2 || r.ignoreReList[1].String() != "^b" {
+		t.Fatalf("recompiling should rebuild the ignore list, got %v", r.ignoreReList)
+	}
+}
+
 func TestRuleCompileErrors(t *testing.T) {
 	r := Rule{Pattern: "["}
 	if err := r.compile(); err == nil {
@@ -151,4 +183,835 @@ block_spacing {
 	if !cfg.Spacing.AllowCompactKind("variable") || !cfg.Spacing.AllowCompactKind("output") {
 		t.Fatalf("allow_compact not applied")
 	}
+	if cfg.Spacing.MaxLines() != 0 {
+		t.Fatalf("max_blank_lines should default to no limit")
+	}
+	if _, ok := cfg.Spacing.NestedMin("lifecycle"); ok {
+		t.Fatalf("nested spacing should be off by default")
+	}
+
+	path = writeTempFile(t, dir, "nested.hcl", `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+
+block_spacing {
+  max_blank_lines = 2
+  nested { blocks = ["lifecycle", "dynamic"] }
+}
+`)
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if cfg.Spacing.MaxLines() != 2 {
+		t.Fatalf("max_blank_lines mismatch: %d", cfg.Spacing.MaxLines())
+	}
+	if n, ok := cfg.Spacing.NestedMin("dynamic"); !ok || n != 1 {
+		t.Fatalf("nested dynamic: %d %v", n, ok)
+	}
+	if _, ok := cfg.Spacing.NestedMin("ingress"); ok {
+		t.Fatalf("nested spacing should only cover the listed blocks")
+	}
+
+	path = writeTempFile(t, dir, "bad.hcl", `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+
+block_spacing {
+  min_blank_lines = 2
+  max_blank_lines = 1
+}
+`)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "max_blank_lines") {
+		t.Fatalf("expected max < min error, got %v", err)
+	}
+}
+
+func TestRenameMapRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, RenamesFile)
+
+	m, err := LoadRenameMap(path)
+	if err != nil {
+		t.Fatalf("load missing map: %v", err)
+	}
+	if !m.Empty() {
+		t.Fatalf("missing map should be empty")
+	}
+	m.Renames["aws_instance.Web1"] = "web_primary"
+	m.Files["Bad-Name.tf"] = "bad_name.tf"
+	m.Providers["modules/db:aws_s3_bucket.logs"] = ""
+	if err := m.Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	got, err := LoadRenameMap(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got.Renames["aws_instance.Web1"] != "web_primary" || got.Files["Bad-Name.tf"] != "bad_name.tf" {
+		t.Fatalf("round trip mismatch: %+v", got)
+	}
+	if v, ok := got.Providers["modules/db:aws_s3_bucket.logs"]; !ok || v != "" {
+		t.Fatalf("provider skip not preserved: %+v", got.Providers)
+	}
+}
+
+func TestLoadRenamesMap(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempFile(t, dir, "tfsuit.hcl", `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+
+renames = {
+  "aws_instance.Web1" = "web_primary"
+}
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if cfg.Renames["aws_instance.Web1"] != "web_primary" {
+		t.Fatalf("renames map not decoded: %v", cfg.Renames)
+	}
+}
+
+func TestFixSettings(t *testing.T) {
+	dir := t.TempDir()
+	base := `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`
+	cfg, err := Load(writeTempFile(t, dir, "default.hcl", base))
+	if err != nil {
+		t.Fatalf("load default: %v", err)
+	}
+	if cfg.Fix == nil || cfg.Fix.OnCollision != CollisionError {
+		t.Fatalf("on_collision should default to error: %+v", cfg.Fix)
+	}
+
+	cfg, err = Load(writeTempFile(t, dir, "suffix.hcl", base+`fix { on_collision = "suffix" }`))
+	if err != nil {
+		t.Fatalf("load suffix: %v", err)
+	}
+	if cfg.Fix.OnCollision != CollisionSuffix {
+		t.Fatalf("on_collision not loaded: %+v", cfg.Fix)
+	}
+
+	if _, err := Load(writeTempFile(t, dir, "bad.hcl", base+`fix { on_collision = "rename" }`)); err == nil {
+		t.Fatalf("expected error for unknown on_collision")
+	}
+}
+
+func TestLoadExtendsMerges(t *testing.T) {
+	dir := t.TempDir()
+	writeTempFile(t, dir, "base.hcl", `
+variables {
+  pattern      = "^[a-z_]+$"
+  ignore_exact = ["legacy"]
+}
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = "^[a-z_]+$" }
+
+block_spacing {
+  min_blank_lines = 2
+  allow_compact   = ["variable"]
+}
+`)
+	if err := os.Mkdir(filepath.Join(dir, "team"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	path := writeTempFile(t, dir, filepath.Join("team", "tfsuit.hcl"), `
+extends = ["../base.hcl"]
+
+variables {
+  ignore_exact = ["TeamVar"]
+}
+resources { pattern = "^[a-z0-9_]+$" }
+
+block_spacing {
+  allow_compact = ["output"]
+}
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Variables.Pattern != "^[a-z_]+$" {
+		t.Fatalf("inherited pattern lost: %s", cfg.Variables.Pattern)
+	}
+	if !cfg.Variables.IsIgnored("legacy") || !cfg.Variables.IsIgnored("TeamVar") {
+		t.Fatalf("ignore lists should be unioned: %v", cfg.Variables.IgnoreExact)
+	}
+	if !cfg.Resources.Matches("web1") {
+		t.Fatalf("pattern override not applied")
+	}
+	if cfg.Spacing.MinLines() != 2 || !cfg.Spacing.AllowCompactKind("variable") || !cfg.Spacing.AllowCompactKind("output") {
+		t.Fatalf("block_spacing not merged: %+v", cfg.Spacing)
+	}
+}
+
+func TestLoadExtendsErrors(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTempFile(t, dir, "a.hcl", `extends = ["b.hcl"]`)
+	writeTempFile(t, dir, "b.hcl", `extends = ["a.hcl"]`)
+	if _, err := Load(a); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected extends cycle error, got %v", err)
+	}
+
+	partial := writeTempFile(t, dir, "partial.hcl", `variables { pattern = ".*" }`)
+	if _, err := Load(partial); err == nil || !strings.Contains(err.Error(), "missing outputs block") {
+		t.Fatalf("expected missing block error, got %v", err)
+	}
+}
+
+func TestLoadCollectsCompileErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempFile(t, dir, "tfsuit.hcl", `
+variables { pattern = "(" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources {
+  pattern      = ".*"
+  ignore_regex = ["[z-a]"]
+}
+fix { on_collision = "maybe" }
+`)
+	_, err := Load(path)
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+	for _, want := range []string{"variables: invalid pattern", "resources: invalid ignore_regex", "fix.on_collision"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("missing %q in %v", want, err)
+		}
+	}
+}
+
+func TestTreeNestedConfigs(t *testing.T) {
+	dir := t.TempDir()
+	base := writeTempFile(t, dir, "tfsuit.hcl", `
+variables { pattern = "^[a-z]+$" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`)
+	for _, sub := range []string{"legacy", filepath.Join("legacy", "deep"), "other"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	writeTempFile(t, dir, filepath.Join("legacy", "tfsuit.hcl"), `variables { pattern = "^[A-Za-z]+$" }`)
+
+	cfg, err := Load(base)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	tree := NewTree(dir, cfg)
+	root, err := tree.For(dir)
+	if err != nil || root != cfg {
+		t.Fatalf("root should use the base config: %v", err)
+	}
+	other, _ := tree.For(filepath.Join(dir, "other"))
+	if other != cfg {
+		t.Fatalf("directory without nested config should inherit the base")
+	}
+	deep, err := tree.For(filepath.Join(dir, "legacy", "deep"))
+	if err != nil {
+		t.Fatalf("For: %v", err)
+	}
+	if !deep.Variables.Matches("CamelCase") || cfg.Variables.Matches("CamelCase") {
+		t.Fatalf("nested config should only apply to its subtree")
+	}
+	if deep.Path() != filepath.Join(dir, "legacy", "tfsuit.hcl") {
+		t.Fatalf("unexpected nested config path %s", deep.Path())
+	}
+}
+
+func TestTreeRootedAtBaseConfig(t *testing.T) {
+	dir := t.TempDir()
+	base := writeTempFile(t, dir, "tfsuit.hcl", `
+variables { pattern = "^[a-z]+$" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`)
+	net := filepath.Join(dir, "legacy", "net")
+	if err := os.MkdirAll(net, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	// solo la capa propia: pattern viene de la config raíz
+	writeTempFile(t, dir, filepath.Join("legacy", "tfsuit.hcl"), `variables { ignore_exact = ["LegacyName"] }`)
+
+	cfg, err := Load(base)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	eff, err := NewTree(net, cfg).For(net)
+	if err != nil {
+		t.Fatalf("For: %v", err)
+	}
+	if !eff.Variables.IsIgnored("LegacyName") || eff.Variables.Matches("Other") {
+		t.Fatalf("legacy/tfsuit.hcl should be layered over the base config")
+	}
+	if eff.Path() != filepath.Join(dir, "legacy", "tfsuit.hcl") {
+		t.Fatalf("unexpected nested config path %s", eff.Path())
+	}
+
+	// fuera del directorio de la base el árbol empieza en el target
+	other := t.TempDir()
+	if got, _ := NewTree(other, cfg).For(other); got != cfg {
+		t.Fatalf("target outside the base directory should use the base config")
+	}
+}
+
+func TestDiscoverOutsideGitUsesNearest(t *testing.T) {
+	outer := t.TempDir()
+	inner := filepath.Join(outer, "project")
+	if err := os.Mkdir(inner, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeTempFile(t, outer, "tfsuit.hcl", ``) // p. ej. un ~/tfsuit.hcl olvidado
+	nearest := writeTempFile(t, inner, "tfsuit.hcl", ``)
+	if got, err := Discover(inner); err != nil || got != nearest {
+		t.Fatalf("outside a git repo the nearest config should win, got %s (%v)", got, err)
+	}
+}
+
+func TestDiscoverWalksUpToGitRoot(t *testing.T) {
+	repo := t.TempDir()
+	target := filepath.Join(repo, "infra", "network")
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir .git: %v", err)
+	}
+	if _, err := Discover(target); err == nil {
+		t.Fatalf("expected error when no config exists")
+	}
+
+	jsonCfg := writeTempFile(t, repo, "tfsuit.json", `{}`)
+	if got, err := Discover(target); err != nil || got != jsonCfg {
+		t.Fatalf("expected %s, got %s (%v)", jsonCfg, got, err)
+	}
+	writeTempFile(t, repo, filepath.Join("infra", ".tfsuit.hcl"), ``)
+	if got, _ := Discover(target); got != jsonCfg {
+		t.Fatalf("outermost config should be the base, got %s", got)
+	}
+	preferred := writeTempFile(t, repo, "tfsuit.hcl", ``)
+	if got, _ := Discover(filepath.Join(target, "main.tf")); got != preferred {
+		t.Fatalf("tfsuit.hcl should be preferred, got %s", got)
+	}
+
+	// nothing above the git root is considered
+	nested := filepath.Join(repo, "sub")
+	if err := os.MkdirAll(filepath.Join(nested, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if _, err := Discover(nested); err == nil {
+		t.Fatalf("discovery should stop at the git root")
+	}
+}
+
+func TestPresetsCompile(t *testing.T) {
+	for _, p := range Presets() {
+		layer, err := presetLayer(p.Name)
+		if err != nil {
+			t.Fatalf("preset %s: %v", p.Name, err)
+		}
+		if err := layer.compileRules(); err != nil {
+			t.Fatalf("preset %s does not compile: %v", p.Name, err)
+		}
+	}
+}
+
+func TestLoadPresetWithOverrides(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempFile(t, dir, "tfsuit.hcl", `
+preset = "snake-case-strict"
+
+modules {
+  pattern = "^[a-z][a-z0-9-]*$"
+}
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Variables.Matches("bad__name") || !cfg.Variables.Matches("good_name") {
+		t.Fatalf("preset variables rule not applied")
+	}
+	if !cfg.Modules.Matches("vpc-main") {
+		t.Fatalf("module override not applied")
+	}
+	if !cfg.Modules.RequiresProvider() {
+		t.Fatalf("preset should keep require_provider defaults")
+	}
+
+	bad := writeTempFile(t, dir, "bad.hcl", `preset = "nope"`)
+	if _, err := Load(bad); err == nil || !strings.Contains(err.Error(), "unknown preset") {
+		t.Fatalf("expected unknown preset error, got %v", err)
+	}
+}
+
+func TestNeverMatches(t *testing.T) {
+	never := []string{"^$", "a^b", "^a$b", "[^\\x00-\\x{10FFFF}]", "$^"}
+	for _, p := range never {
+		if !neverMatches(p) {
+			t.Fatalf("%q should be reported as never matching", p)
+		}
+	}
+	fine := []string{".*", "^$|^a", "^[a-z_]+$", "^(this)?$", "", "^", "x*", "^(a|b)$"}
+	for _, p := range fine {
+		if neverMatches(p) {
+			t.Fatalf("%q can match a name", p)
+		}
+	}
+}
+
+func TestValidateReportsIssues(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempFile(t, dir, "tfsuit.hcl", `
+variables {
+  pattern      = "^$"
+  ignore_exact = ["used", "unused"]
+}
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources {
+  pattern      = ".*"
+  ignore_regex = ["^tmp_"]
+}
+block_spacing { allow_compact = ["varible"] }
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var got []string
+	for _, issue := range cfg.Validate(map[string][]string{"variable": {"used"}, "resource": {"web"}}) {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		`error: block_spacing.allow_compact: unknown kind "varible" (valid: variable, output, module, resource, data, locals, provider, terraform, moved, import, removed, check)`,
+		`warning: variables.pattern "^$" can never match a name`,
+		`warning: variables.ignore_exact "unused" matches nothing in the tree`,
+		`warning: resources.ignore_regex "^tmp_" matches nothing in the tree`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected issues:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestResolvedRoundTrips(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempFile(t, dir, "tfsuit.hcl", `
+preset = "snake-case"
+
+renames = {
+  "aws_instance.Web" = "web"
+}
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	resolved := cfg.Resolved()
+	if resolved.Preset != "" || resolved.Modules.RequireProvider == nil || !*resolved.Modules.RequireProvider {
+		t.Fatalf("resolved config should spell out defaults: %+v", resolved.Modules)
+	}
+	hclOut := writeTempFile(t, dir, "resolved.hcl", string(resolved.HCL()))
+	again, err := Load(hclOut)
+	if err != nil {
+		t.Fatalf("resolved HCL does not load: %v\n%s", err, resolved.HCL())
+	}
+	if again.Files.Pattern != cfg.Files.Pattern || again.Renames["aws_instance.Web"] != "web" {
+		t.Fatalf("resolved HCL lost settings:\n%s", resolved.HCL())
+	}
+	data, err := resolved.JSON()
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+	jsonOut := writeTempFile(t, dir, "resolved.json", string(data))
+	if _, err := Load(jsonOut); err != nil {
+		t.Fatalf("resolved JSON does not load: %v\n%s", err, data)
+	}
+}
+
+func TestTemplateRule(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempFile(t, dir, "tfsuit.hcl", `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources {
+  template = "{env}_{service}_{purpose}"
+
+  segment "env" {
+    values = ["dev", "stg", "prd"]
+  }
+  segment "purpose" {
+    pattern = "[a-z]+"
+    default = "main"
+  }
+}
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	r := cfg.Resources
+	if !r.Matches("prd_billing_logs") || r.Matches("test_billing_logs") {
+		t.Fatalf("template regexp mismatch")
+	}
+	cases := map[string]string{
+		"test_billing_logs": "segment {env} is 'test', expected one of dev, stg, prd",
+		"dev_billing":       `expected "_" after {service}`,
+		"dev_billing_logs2": "segment {purpose} is 'logs2', expected [a-z]+",
+		"dev__logs":         "segment {service} is missing",
+	}
+	for name, want := range cases {
+		if got := r.Explain("resource", name); !strings.Contains(got, want) {
+			t.Fatalf("Explain(%s) = %q, want %q", name, got, want)
+		}
+	}
+
+	suggest := map[string]string{
+		"billing_logs_prd": "prd_billing_logs", // reordered
+		"Billing-Logs":     "stg_billing_logs", // env inferred from the path
+		"billing":          "stg_billing_main", // purpose from its default
+	}
+	for name, want := range suggest {
+		got, ok := r.SuggestName(name, filepath.Join("live", "stg", "main.tf"))
+		if !ok || got != want {
+			t.Fatalf("SuggestName(%s) = %q %v, want %q", name, got, ok, want)
+		}
+	}
+	if _, ok := r.SuggestName("billing", "main.tf"); ok {
+		t.Fatalf("env cannot be inferred without a hint")
+	}
+}
+
+func TestRuleLimits(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempFile(t, dir, "tfsuit.hcl", `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources {
+  pattern               = ".*"
+  max_length            = 12
+  min_length            = 3
+  forbidden_words       = ["temp", "do_not_use"]
+  disallow_type_stutter = true
+}
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	r := cfg.Resources
+	cases := map[string][]string{
+		"logs":           nil,
+		"template":       nil,
+		"ab":             {"resource 'ab' is 2 characters long, min_length is 3"},
+		"billing_temp":   {"resource 'billing_temp' contains forbidden word 'temp'"},
+		"x_Do_Not_Use":   {"resource 'x_Do_Not_Use' contains forbidden word 'do_not_use'"},
+		"s3_bucket_logs": {"resource 's3_bucket_logs' is 14 characters long, max_length is 12", "resource 's3_bucket_logs' repeats its type aws_s3_bucket ('s3_bucket')"},
+		"logs_bucket":    nil, // una sola palabra del tipo no es stutter
+	}
+	for name, want := range cases {
+		got := r.Violations("resource", "aws_s3_bucket", name)
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Fatalf("Violations(%s) = %q, want %q", name, got, want)
+		}
+	}
+	if got := TypeStutter("aws_iam_role", "role_admin"); got != "" {
+		t.Fatalf("TypeStutter(aws_iam_role, role_admin) = %q, want none", got)
+	}
+	if got := TypeStutter("aws_instance", "instance"); got != "instance" {
+		t.Fatalf("TypeStutter(aws_instance, instance) = %q, want instance", got)
+	}
+
+	strip := map[string]string{
+		"s3_bucket_logs": "logs",
+		"logs-bucket":    "logs-bucket",
+		"bucket_logs":    "bucket_logs",
+		"app_s3_data":    "app_s3_data",
+		"app_s3_bucket":  "app",
+		"s3_bucket":      "this",
+		"logs":           "logs",
+	}
+	for name, want := range strip {
+		if got := StripStutter("aws_s3_bucket", name); got != want {
+			t.Fatalf("StripStutter(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestAttributeRulesMergeAndPrint(t *testing.T) {
+	dir := t.TempDir()
+	writeTempFile(t, dir, "base.hcl", `
+preset = "snake-case"
+
+attribute "aws_s3_bucket" "bucket" {
+  pattern    = "^[a-z0-9-]+$"
+  max_length = 63
+}
+`)
+	path := writeTempFile(t, dir, "tfsuit.hcl", `
+extends = ["base.hcl"]
+
+attribute "aws_s3_bucket" "bucket" {
+  forbidden_words = ["test"]
+}
+
+attribute "*" "tags.Name" {
+  template = "{env}-{service}"
+}
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	rules := cfg.AttributeRules("aws_s3_bucket")
+	if len(rules) != 2 || rules[0].Attribute != "bucket" || strings.Join(rules[1].Path(), ".") != "tags.Name" {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+	if r := rules[0]; r.MaxLength != 63 || len(r.ForbiddenWords) != 1 || !r.Matches("logs-bucket") {
+		t.Fatalf("bucket rule not merged: %+v", r)
+	}
+	if len(cfg.AttributeRules("aws_instance")) != 1 {
+		t.Fatalf("wildcard rule should apply to every type")
+	}
+
+	for _, format := range []string{"hcl", "json"} {
+		var out []byte
+		if format == "hcl" {
+			out = cfg.Resolved().HCL()
+		} else if out, err = cfg.Resolved().JSON(); err != nil {
+			t.Fatalf("JSON: %v", err)
+		}
+		again, err := Load(writeTempFile(t, dir, "resolved."+format, string(out)))
+		if err != nil {
+			t.Fatalf("resolved %s does not load: %v\n%s", format, err, out)
+		}
+		if len(again.Attributes) != 2 || again.Attributes[0].MaxLength != 63 {
+			t.Fatalf("resolved %s lost attribute rules:\n%s", format, out)
+		}
+	}
+
+	bad := writeTempFile(t, dir, "bad.hcl", `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+attribute "aws_s3_bucket" "tags[Name]" { pattern = ".*" }
+`)
+	if _, err := Load(bad); err == nil || !strings.Contains(err.Error(), `expected name, name.key or name["key"]`) {
+		t.Fatalf("want attribute path error, got %v", err)
+	}
+}
+
+func TestProviderAssignment(t *testing.T) {
+	dir := t.TempDir()
+	writeTempFile(t, dir, "base.hcl", `
+preset = "snake-case"
+
+provider_assignment {
+  ambiguous = "most_used"
+
+  assign "aws.us_east_1" {
+    types = ["aws_"]
+  }
+}
+`)
+	path := writeTempFile(t, dir, "tfsuit.hcl", `
+extends = ["base.hcl"]
+
+provider_assignment {
+  assign "aws.eu_west_1" {
+    files = ["stacks/**/eu/*.tf", "*_eu.tf"]
+  }
+  assign "google.main" {
+    directories = ["gcp"]
+  }
+}
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	p := cfg.ProviderAssignment
+	if !p.MostUsed() {
+		t.Fatalf("ambiguous not inherited: %+v", p)
+	}
+	cases := []struct{ rel, typ, want string }{
+		{"stacks/eu/main.tf", "aws_s3_bucket", "aws.eu_west_1"},
+		{"stacks/app/x/eu/main.tf", "aws_s3_bucket", "aws.eu_west_1"},
+		{"network_eu.tf", "", "aws.eu_west_1"},
+		{"gcp/sub/main.tf", "google_storage_bucket", "google.main"},
+		{"main.tf", "aws_instance", "aws.us_east_1"},
+		{"main.tf", "", ""},
+	}
+	for _, tc := range cases {
+		if got, _ := p.AliasFor(tc.rel, tc.typ); got != tc.want {
+			t.Errorf("AliasFor(%q, %q) = %q, want %q", tc.rel, tc.typ, got, tc.want)
+		}
+	}
+	if (*ProviderAssignment)(nil).MostUsed() != true {
+		t.Fatalf("without provider_assignment the most used alias should win")
+	}
+
+	again, err := Load(writeTempFile(t, dir, "resolved.hcl", string(cfg.Resolved().HCL())))
+	if err != nil || len(again.ProviderAssignment.Assign) != 3 || again.ProviderAssignment.Assign[0].Alias != "aws.eu_west_1" {
+		t.Fatalf("resolved config lost provider_assignment: %v\n%s", err, cfg.Resolved().HCL())
+	}
+
+	bad := writeTempFile(t, dir, "bad.hcl", `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+provider_assignment { ambiguous = "first" }
+`)
+	if _, err := Load(bad); err == nil || !strings.Contains(err.Error(), "invalid provider_assignment.ambiguous") {
+		t.Fatalf("want ambiguous policy error, got %v", err)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeTempFile(t, dir, "base.hcl", `
+preset = "snake-case"
+
+layout {
+  variable = ["variables.tf"]
+  output   = ["outputs.tf"]
+}
+`)
+	path := writeTempFile(t, dir, "tfsuit.hcl", `
+extends = ["base.hcl"]
+
+layout {
+  variable = ["vars_*.tf"]
+  provider = ["providers.tf", "providers_*.tf"]
+}
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	l := cfg.Layout
+	cases := []struct {
+		kind, file string
+		want       bool
+	}{
+		{"variable", "vars_network.tf", true},
+		{"variable", "variables.tf", false}, // replaced by the extending file
+		{"output", "outputs.tf", true},
+		{"output", "main.tf", false},
+		{"provider", "providers_eu.tf", true},
+		{"resource", "anything.tf", true},
+	}
+	for _, tc := range cases {
+		if got := l.Allows(tc.kind, tc.file); got != tc.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", tc.kind, tc.file, got, tc.want)
+		}
+	}
+	if l.Target("provider") != "providers.tf" || l.Target("variable") != "" {
+		t.Fatalf("unexpected targets: %q %q", l.Target("provider"), l.Target("variable"))
+	}
+	issues := cfg.Validate(nil)
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, "layout.variable has no plain file name") {
+		t.Fatalf("unexpected issues: %v", issues)
+	}
+
+	again, err := Load(writeTempFile(t, dir, "resolved.hcl", string(cfg.Resolved().HCL())))
+	if err != nil || len(again.Layout.Provider) != 2 || again.Layout.Output[0] != "outputs.tf" {
+		t.Fatalf("resolved config lost layout: %v\n%s", err, cfg.Resolved().HCL())
+	}
+
+	bad := writeTempFile(t, dir, "bad.hcl", `
+preset = "snake-case"
+layout { variable = ["vars/variables.tf"] }
+`)
+	if _, err := Load(bad); err == nil || !strings.Contains(err.Error(), "file names only") {
+		t.Fatalf("want file name error, got %v", err)
+	}
+}
+
+func TestOrderingArrange(t *testing.T) {
+	names := []string{"lifecycle", "ami", "source", "depends_on", "count", "tags"}
+	first := &Ordering{MetaArguments: MetaFirst}
+	last := &Ordering{MetaArguments: MetaLast}
+	cases := []struct {
+		o    *Ordering
+		kind string
+		want []int
+	}{
+		{first, "resource", []int{4, 3, 0, 1, 2, 5}},
+		{last, "resource", []int{1, 2, 5, 4, 3, 0}},
+		{first, "module", []int{2, 4, 3, 0, 1, 5}},
+		{&Ordering{}, "resource", nil},
+	}
+	for _, tc := range cases {
+		if got := tc.o.Arrange(tc.kind, names); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("Arrange(%s, %s) = %v, want %v", tc.o.MetaArguments, tc.kind, got, tc.want)
+		}
+	}
+
+	dir := t.TempDir()
+	bad := writeTempFile(t, dir, "bad.hcl", `
+preset = "snake-case"
+ordering { sort = ["locals"] }
+`)
+	if _, err := Load(bad); err == nil || !strings.Contains(err.Error(), `ordering.sort: unknown kind "locals"`) {
+		t.Fatalf("want unknown kind error, got %v", err)
+	}
+}
+
+func TestInstanceKeysRule(t *testing.T) {
+	dir := t.TempDir()
+	writeTempFile(t, dir, "base.hcl", `
+preset = "snake-case"
+instance_keys { prefer_for_each = true }
+`)
+	path := writeTempFile(t, dir, "tfsuit.hcl", `
+extends = ["base.hcl"]
+instance_keys { pattern = "^[a-z0-9-]+$" }
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	k := cfg.InstanceKeys
+	if k == nil || !k.ForEachPreferred() || !k.Matches("web-1") || k.Matches("Web_1") {
+		t.Fatalf("instance_keys not merged: %+v", k)
+	}
+	if out := string(cfg.Resolved().HCL()); !strings.Contains(out, "prefer_for_each") {
+		t.Fatalf("prefer_for_each not printed:\n%s", out)
+	}
+
+	base, err := Load(filepath.Join(dir, "base.hcl"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !base.InstanceKeys.Matches("Anything") {
+		t.Fatalf("instance_keys without pattern should accept every key")
+	}
+	plain := writeTempFile(t, dir, "plain.hcl", `preset = "snake-case"`)
+	if cfg, err := Load(plain); err != nil || cfg.InstanceKeys != nil || cfg.InstanceKeys.ForEachPreferred() {
+		t.Fatalf("instance_keys should be off by default: %v", err)
+	}
 }
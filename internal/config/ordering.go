@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ordering keeps blocks of some kinds sorted by label within each file and places the
+// meta-arguments of resource, data and module blocks first or last.
+type Ordering struct {
+	// Sort lists the kinds (variable, output, module, resource, data) kept in alphabetical order.
+	Sort []string `hcl:"sort,optional" json:"sort,omitempty"`
+	// MetaArguments is "first" or "last": where count, for_each, provider, providers, depends_on
+	// and lifecycle go. A module's source and version always stay on top.
+	MetaArguments string `hcl:"meta_arguments,optional" json:"meta_arguments,omitempty"`
+}
+
+// Meta-argument placements accepted by ordering.meta_arguments.
+const (
+	MetaFirst = "first"
+	MetaLast  = "last"
+)
+
+// MetaArguments in the order ordering places them.
+var MetaArguments = []string{"count", "for_each", "provider", "providers", "depends_on", "lifecycle"}
+
+func (o *Ordering) init() error {
+	if o == nil {
+		return nil
+	}
+	switch o.MetaArguments {
+	case "", MetaFirst, MetaLast:
+	default:
+		return fmt.Errorf("invalid ordering.meta_arguments '%s' (valid: first, last)", o.MetaArguments)
+	}
+	for _, kind := range o.Sort {
+		if !contains(Kinds, kind) {
+			return fmt.Errorf("ordering.sort: unknown kind %q (valid: %s)", kind, strings.Join(Kinds, ", "))
+		}
+	}
+	return nil
+}
+
+// Sorts reports whether blocks of kind are kept in alphabetical order.
+func (o *Ordering) Sorts(kind string) bool {
+	return o != nil && contains(o.Sort, kind)
+}
+
+// Arrange returns the order the items of a resource, data or module body should follow, as
+// indexes into names (attribute names and nested block types, in source order). It returns
+// nil when meta_arguments is unset.
+func (o *Ordering) Arrange(kind string, names []string) []int {
+	if o == nil || o.MetaArguments == "" {
+		return nil
+	}
+	rank := func(name string) int {
+		for i, m := range MetaArguments {
+			if name == m {
+				return i
+			}
+		}
+		return -1
+	}
+	var pinned, meta, rest []int
+	for i, name := range names {
+		switch {
+		case kind == "module" && (name == "source" || name == "version"):
+			pinned = append(pinned, i)
+		case rank(name) >= 0:
+			meta = append(meta, i)
+		default:
+			rest = append(rest, i)
+		}
+	}
+	// orden canónico entre meta-argumentos, estable para bloques repetidos
+	for i := 1; i < len(meta); i++ {
+		for j := i; j > 0 && rank(names[meta[j]]) < rank(names[meta[j-1]]); j-- {
+			meta[j], meta[j-1] = meta[j-1], meta[j]
+		}
+	}
+	out := pinned
+	if o.MetaArguments == MetaFirst {
+		out = append(append(out, meta...), rest...)
+	} else {
+		out = append(append(out, rest...), meta...)
+	}
+	return out
+}
+
+func mergeOrdering(base, over *Ordering) *Ordering {
+	if base == nil && over == nil {
+		return nil
+	}
+	o := &Ordering{}
+	for _, p := range []*Ordering{base, over} {
+		if p == nil {
+			continue
+		}
+		o.Sort = union(o.Sort, p.Sort)
+		if p.MetaArguments != "" {
+			o.MetaArguments = p.MetaArguments
+		}
+	}
+	return o
+}
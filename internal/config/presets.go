@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Preset is a named set of rules shipped with tfsuit, selected with `preset = "<name>"`.
+type Preset struct {
+	Name        string
+	Description string
+	Source      string // HCL, decoded like any other config layer
+}
+
+const (
+	snakeStrict = `^[a-z][a-z0-9]*(_[a-z0-9]+)*$`
+	snakeFile   = `^[a-z][a-z0-9]*(_[a-z0-9]+)*\\.tf$`
+)
+
+var presets = []Preset{
+	{
+		Name:        "snake-case",
+		Description: "lowercase letters, digits and underscores for every kind and file name",
+		Source: `
+variables { pattern = "^[a-z0-9_]+$" }
+outputs   { pattern = "^[a-z0-9_]+$" }
+modules   { pattern = "^[a-z0-9_]+$" }
+resources { pattern = "^[a-z0-9_]+$" }
+data      { pattern = "^[a-z0-9_]+$" }
+files     { pattern = "^[a-z0-9_]+\\.tf$" }
+`,
+	},
+	{
+		Name:        "snake-case-strict",
+		Description: "snake_case starting with a letter, no leading, trailing or doubled underscores",
+		Source: `
+variables { pattern = "` + snakeStrict + `" }
+outputs   { pattern = "` + snakeStrict + `" }
+modules   { pattern = "` + snakeStrict + `" }
+resources { pattern = "` + snakeStrict + `" }
+data      { pattern = "` + snakeStrict + `" }
+files     { pattern = "` + snakeFile + `" }
+`,
+	},
+	{
+		Name:        "kebab-case-modules",
+		Description: "snake-case-strict, but module calls are kebab-case (vpc-main)",
+		Source: `
+variables { pattern = "` + snakeStrict + `" }
+outputs   { pattern = "` + snakeStrict + `" }
+modules   { pattern = "^[a-z][a-z0-9]*(-[a-z0-9]+)*$" }
+resources { pattern = "` + snakeStrict + `" }
+data      { pattern = "` + snakeStrict + `" }
+files     { pattern = "` + snakeFile + `" }
+`,
+	},
+	{
+		Name:        "terraform-best-practices",
+		Description: "terraform-best-practices.com: snake-case-strict, labels don't repeat the resource type",
+		Source: `
+variables { pattern = "` + snakeStrict + `" }
+outputs   { pattern = "` + snakeStrict + `" }
+modules   { pattern = "` + snakeStrict + `" }
+resources {
+  pattern               = "` + snakeStrict + `"
+  disallow_type_stutter = true
+}
+data {
+  pattern               = "` + snakeStrict + `"
+  disallow_type_stutter = true
+}
+files     { pattern = "` + snakeFile + `" }
+`,
+	},
+	{
+		Name:        "single-resource-module",
+		Description: "single-resource modules: every resource and data source is named this",
+		Source: `
+variables { pattern = "` + snakeStrict + `" }
+outputs   { pattern = "` + snakeStrict + `" }
+modules   { pattern = "` + snakeStrict + `" }
+resources { pattern = "^this$" }
+data      { pattern = "^this$" }
+files     { pattern = "` + snakeFile + `" }
+`,
+	},
+	{
+		Name:        "aws-cloudposse",
+		Description: "Cloud Posse modules: snake_case, resources and data sources named default",
+		Source: `
+variables { pattern = "` + snakeStrict + `" }
+outputs   { pattern = "` + snakeStrict + `" }
+modules   { pattern = "` + snakeStrict + `" }
+resources { pattern = "^default$" }
+data      { pattern = "^default$" }
+files     { pattern = "` + snakeFile + `" }
+`,
+	},
+}
+
+// Presets lists the built-in presets, sorted by name.
+func Presets() []Preset {
+	out := append([]Preset(nil), presets...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// LookupPreset returns the built-in preset called name.
+func LookupPreset(name string) (Preset, bool) {
+	for _, p := range presets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+func presetLayer(name string) (*Config, error) {
+	p, ok := LookupPreset(name)
+	if !ok {
+		names := make([]string, 0, len(presets))
+		for _, p := range Presets() {
+			names = append(names, p.Name)
+		}
+		return nil, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return decodeSource("preset:"+p.Name+".hcl", []byte(p.Source))
+}
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"encoding/json"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Resolved returns the fully resolved config: layers merged, defaults applied and every
+// require_provider and block_spacing.enabled spelled out. preset and extends are dropped since
+// they are already applied.
+func (c *Config) Resolved() *Config {
+	out := &Config{path: c.path}
+	out.merge(c)
+	for _, nr := range c.namedRules() {
+		if nr.rule == nil {
+			continue
+		}
+		req := nr.rule.RequiresProvider()
+		out.ruleFor(nr.block).RequireProvider = &req
+	}
+	if out.Spacing == nil {
+		out.Spacing = &BlockSpacing{}
+	}
+	enabled := c.Spacing.EnabledValue()
+	out.Spacing.Enabled = &enabled
+	out.Spacing.MinBlankLines = c.Spacing.MinLines()
+	if out.Fix == nil {
+		out.Fix = &FixSettings{OnCollision: CollisionError}
+	}
+	return out
+}
+
+func (c *Config) ruleFor(block string) *Rule {
+	for _, nr := range c.namedRules() {
+		if nr.block == block {
+			return nr.rule
+		}
+	}
+	return nil
+}
+
+// JSON renders the config in the tfsuit.json format.
+func (c *Config) JSON() ([]byte, error) {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// HCL renders the config in the tfsuit.hcl format.
+func (c *Config) HCL() []byte {
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+	first := true
+	section := func() {
+		if !first {
+			body.AppendNewline()
+		}
+		first = false
+	}
+	if c.Preset != "" {
+		first = false
+		body.SetAttributeValue("preset", cty.StringVal(c.Preset))
+	}
+	if len(c.Extends) > 0 {
+		first = false
+		body.SetAttributeValue("extends", stringList(c.Extends))
+	}
+
+	for _, nr := range c.namedRules() {
+		r := nr.rule
+		if r == nil {
+			continue
+		}
+		section()
+		writeRule(body.AppendNewBlock(nr.block, nil).Body(), r)
+	}
+
+	for _, a := range c.Attributes {
+		section()
+		writeRule(body.AppendNewBlock("attribute", []string{a.Type, a.Attribute}).Body(), &a.Rule)
+	}
+
+	if bs := c.Spacing; bs != nil {
+		section()
+		sb := body.AppendNewBlock("block_spacing", nil).Body()
+		if bs.Enabled != nil {
+			sb.SetAttributeValue("enabled", cty.BoolVal(*bs.Enabled))
+		}
+		if bs.MinBlankLines > 0 {
+			sb.SetAttributeValue("min_blank_lines", cty.NumberIntVal(int64(bs.MinBlankLines)))
+		}
+		if bs.MaxBlankLines > 0 {
+			sb.SetAttributeValue("max_blank_lines", cty.NumberIntVal(int64(bs.MaxBlankLines)))
+		}
+		if len(bs.AllowCompact) > 0 {
+			sb.SetAttributeValue("allow_compact", stringList(bs.AllowCompact))
+		}
+		if n := bs.Nested; n != nil {
+			nb := sb.AppendNewBlock("nested", nil).Body()
+			if n.MinBlankLines > 0 {
+				nb.SetAttributeValue("min_blank_lines", cty.NumberIntVal(int64(n.MinBlankLines)))
+			}
+			if len(n.Blocks) > 0 {
+				nb.SetAttributeValue("blocks", stringList(n.Blocks))
+			}
+		}
+	}
+
+	if c.Fix != nil && c.Fix.OnCollision != "" {
+		section()
+		fb := body.AppendNewBlock("fix", nil).Body()
+		fb.SetAttributeValue("on_collision", cty.StringVal(c.Fix.OnCollision))
+	}
+
+	if t := c.Tags; t != nil {
+		section()
+		tb := body.AppendNewBlock("tags", nil).Body()
+		if len(t.Required) > 0 {
+			tb.SetAttributeValue("required", stringList(t.Required))
+		}
+		if t.KeyCase != "" {
+			tb.SetAttributeValue("key_case", cty.StringVal(t.KeyCase))
+		}
+		if len(t.Values) > 0 {
+			tb.SetAttributeValue("values", stringMap(t.Values))
+		}
+		if len(t.Types) > 0 {
+			tb.SetAttributeValue("types", stringList(t.Types))
+		}
+		if len(t.ExcludeTypes) > 0 {
+			tb.SetAttributeValue("exclude_types", stringList(t.ExcludeTypes))
+		}
+		if t.Attribute != "" {
+			tb.SetAttributeValue("attribute", cty.StringVal(t.Attribute))
+		}
+		if len(t.FromVariables) > 0 {
+			tb.SetAttributeValue("from_variables", stringMap(t.FromVariables))
+		}
+	}
+
+	if u := c.Unused; u != nil {
+		section()
+		ub := body.AppendNewBlock("unused", nil).Body()
+		for _, opt := range []struct {
+			name string
+			on   *bool
+		}{{"variables", u.Variables}, {"locals", u.Locals}, {"data", u.Data}} {
+			if opt.on != nil {
+				ub.SetAttributeValue(opt.name, cty.BoolVal(*opt.on))
+			}
+		}
+	}
+
+	if p := c.ProviderAssignment; p != nil {
+		section()
+		pb := body.AppendNewBlock("provider_assignment", nil).Body()
+		if p.Ambiguous != "" {
+			pb.SetAttributeValue("ambiguous", cty.StringVal(p.Ambiguous))
+		}
+		for _, a := range p.Assign {
+			ab := pb.AppendNewBlock("assign", []string{a.Alias}).Body()
+			if len(a.Files) > 0 {
+				ab.SetAttributeValue("files", stringList(a.Files))
+			}
+			if len(a.Directories) > 0 {
+				ab.SetAttributeValue("directories", stringList(a.Directories))
+			}
+			if len(a.Types) > 0 {
+				ab.SetAttributeValue("types", stringList(a.Types))
+			}
+		}
+	}
+
+	if p := c.ProviderChain; p != nil {
+		section()
+		pb := body.AppendNewBlock("provider_chain", nil).Body()
+		for _, opt := range []struct {
+			name string
+			on   *bool
+		}{{"mappings", p.Mappings}, {"references", p.References}} {
+			if opt.on != nil {
+				pb.SetAttributeValue(opt.name, cty.BoolVal(*opt.on))
+			}
+		}
+	}
+
+	if m := c.ModuleSources; m != nil && m.VendorDir != "" {
+		section()
+		mb := body.AppendNewBlock("module_sources", nil).Body()
+		mb.SetAttributeValue("vendor_dir", cty.StringVal(m.VendorDir))
+	}
+
+	if l := c.Layout; l != nil {
+		section()
+		lb := body.AppendNewBlock("layout", nil).Body()
+		for _, k := range l.kinds() {
+			if len(*k.files) > 0 {
+				lb.SetAttributeValue(k.kind, stringList(*k.files))
+			}
+		}
+	}
+
+	if o := c.Ordering; o != nil {
+		section()
+		ob := body.AppendNewBlock("ordering", nil).Body()
+		if len(o.Sort) > 0 {
+			ob.SetAttributeValue("sort", stringList(o.Sort))
+		}
+		if o.MetaArguments != "" {
+			ob.SetAttributeValue("meta_arguments", cty.StringVal(o.MetaArguments))
+		}
+	}
+
+	if len(c.Renames) > 0 {
+		section()
+		body.SetAttributeValue("renames", stringMap(c.Renames))
+	}
+	return hclwrite.Format(f.Bytes())
+}
+
+func writeRule(rb *hclwrite.Body, r *Rule) {
+	if r.Pattern != "" {
+		rb.SetAttributeValue("pattern", cty.StringVal(r.Pattern))
+	}
+	if r.Template != "" {
+		rb.SetAttributeValue("template", cty.StringVal(r.Template))
+	}
+	if len(r.IgnoreExact) > 0 {
+		rb.SetAttributeValue("ignore_exact", stringList(r.IgnoreExact))
+	}
+	if len(r.IgnoreRegex) > 0 {
+		rb.SetAttributeValue("ignore_regex", stringList(r.IgnoreRegex))
+	}
+	if r.RequireProvider != nil {
+		rb.SetAttributeValue("require_provider", cty.BoolVal(*r.RequireProvider))
+	}
+	if r.MaxLength > 0 {
+		rb.SetAttributeValue("max_length", cty.NumberIntVal(int64(r.MaxLength)))
+	}
+	if r.MinLength > 0 {
+		rb.SetAttributeValue("min_length", cty.NumberIntVal(int64(r.MinLength)))
+	}
+	if len(r.ForbiddenWords) > 0 {
+		rb.SetAttributeValue("forbidden_words", stringList(r.ForbiddenWords))
+	}
+	if r.DisallowTypeStutter != nil {
+		rb.SetAttributeValue("disallow_type_stutter", cty.BoolVal(*r.DisallowTypeStutter))
+	}
+	if r.RequireDescription != nil {
+		rb.SetAttributeValue("require_description", cty.BoolVal(*r.RequireDescription))
+	}
+	if r.RequireType != nil {
+		rb.SetAttributeValue("require_type", cty.BoolVal(*r.RequireType))
+	}
+	if r.RequireSensitiveFor != "" {
+		rb.SetAttributeValue("require_sensitive_for", cty.StringVal(r.RequireSensitiveFor))
+	}
+	if r.DescriptionPattern != "" {
+		rb.SetAttributeValue("description_pattern", cty.StringVal(r.DescriptionPattern))
+	}
+	if r.PreferForEach != nil {
+		rb.SetAttributeValue("prefer_for_each", cty.BoolVal(*r.PreferForEach))
+	}
+	for _, seg := range r.Segments {
+		sb := rb.AppendNewBlock("segment", []string{seg.Name}).Body()
+		if len(seg.Values) > 0 {
+			sb.SetAttributeValue("values", stringList(seg.Values))
+		}
+		if seg.Pattern != "" {
+			sb.SetAttributeValue("pattern", cty.StringVal(seg.Pattern))
+		}
+		if seg.Default != "" {
+			sb.SetAttributeValue("default", cty.StringVal(seg.Default))
+		}
+	}
+}
+
+// stringMap renders m as an object; cty sorts the attributes, so the output is stable.
+func stringMap(m map[string]string) cty.Value {
+	vals := make(map[string]cty.Value, len(m))
+	for k, v := range m {
+		vals[k] = cty.StringVal(v)
+	}
+	return cty.ObjectVal(vals)
+}
+
+func stringList(list []string) cty.Value {
+	vals := make([]cty.Value, len(list))
+	for i, v := range list {
+		vals[i] = cty.StringVal(v)
+	}
+	return cty.ListVal(vals)
+}
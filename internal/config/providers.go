@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ProviderAssignment tells `tfsuit fix` which provider alias to inject, instead of letting the
+// most used alias of the scope win.
+type ProviderAssignment struct {
+	// Ambiguous decides what happens when no assign block matches and several aliases fit:
+	// "error" (default) reports the choices, "most_used" picks the most used alias.
+	Ambiguous string `hcl:"ambiguous,optional" json:"ambiguous,omitempty"`
+	// Assign blocks are tried in order; those of nested configs come first.
+	Assign []*ProviderAssign `hcl:"assign,block" json:"assign,omitempty"`
+}
+
+// ProviderAssign maps blocks to an alias (aws.eu_west_1). Every criterion given must match;
+// within one, any entry does.
+type ProviderAssign struct {
+	Alias string `hcl:"alias,label" json:"alias"`
+	// Files are globs relative to the scanned root; ** spans directories and globs without
+	// a slash match the file name.
+	Files []string `hcl:"files,optional" json:"files,omitempty"`
+	// Directories, relative to the scanned root, include their subdirectories.
+	Directories []string `hcl:"directories,optional" json:"directories,omitempty"`
+	// Types are resource type prefixes (aws_, google_); module calls never match them.
+	Types []string `hcl:"types,optional" json:"types,omitempty"`
+}
+
+// Policies accepted by provider_assignment.ambiguous.
+const (
+	AmbiguousError    = "error"
+	AmbiguousMostUsed = "most_used"
+)
+
+func (p *ProviderAssignment) init() error {
+	if p == nil {
+		return nil
+	}
+	switch p.Ambiguous {
+	case "":
+		p.Ambiguous = AmbiguousError
+	case AmbiguousError, AmbiguousMostUsed:
+	default:
+		return fmt.Errorf("invalid provider_assignment.ambiguous '%s' (valid: error, most_used)", p.Ambiguous)
+	}
+	for _, a := range p.Assign {
+		if !strings.Contains(a.Alias, ".") {
+			return fmt.Errorf("provider_assignment.assign %q: expected TYPE.ALIAS", a.Alias)
+		}
+		for _, g := range a.Files {
+			if _, err := path.Match(strings.ReplaceAll(g, "**", "*"), ""); err != nil {
+				return fmt.Errorf("invalid provider_assignment file glob '%s': %w", g, err)
+			}
+		}
+	}
+	return nil
+}
+
+// MostUsed reports whether ambiguous choices fall back to the most used alias. Without a
+// provider_assignment block that is the historical behaviour.
+func (p *ProviderAssignment) MostUsed() bool {
+	return p == nil || p.Ambiguous == AmbiguousMostUsed
+}
+
+// AliasFor returns the alias assigned to a block of type typ ("" for module calls) in file rel,
+// a slash separated path relative to the scanned root.
+func (p *ProviderAssignment) AliasFor(rel, typ string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	for _, a := range p.Assign {
+		if a.matches(rel, typ) {
+			return a.Alias, true
+		}
+	}
+	return "", false
+}
+
+func (a *ProviderAssign) matches(rel, typ string) bool {
+	if len(a.Files) == 0 && len(a.Directories) == 0 && len(a.Types) == 0 {
+		return false
+	}
+	if len(a.Files) > 0 && !anyOf(a.Files, func(g string) bool { return matchFileGlob(g, rel) }) {
+		return false
+	}
+	if len(a.Directories) > 0 && !anyOf(a.Directories, func(d string) bool { return underDir(d, rel) }) {
+		return false
+	}
+	if len(a.Types) > 0 && (typ == "" || !anyOf(a.Types, func(pre string) bool { return strings.HasPrefix(typ, pre) })) {
+		return false
+	}
+	return true
+}
+
+func anyOf(list []string, ok func(string) bool) bool {
+	for _, v := range list {
+		if ok(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func underDir(dir, rel string) bool {
+	dir = strings.Trim(path.Clean(dir), "/")
+	if dir == "." || dir == "" {
+		return true
+	}
+	return rel == dir || strings.HasPrefix(rel, dir+"/")
+}
+
+// matchFileGlob matches rel against glob, where a ** segment stands for any number of directories.
+func matchFileGlob(glob, rel string) bool {
+	if !strings.Contains(glob, "/") {
+		ok, _ := path.Match(glob, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(glob, "/"), strings.Split(rel, "/"))
+}
+
+func matchSegments(glob, parts []string) bool {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(glob[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(glob[0], parts[0]); !ok {
+			return false
+		}
+		glob, parts = glob[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+func mergeProviderAssignment(base, over *ProviderAssignment) *ProviderAssignment {
+	if base == nil && over == nil {
+		return nil
+	}
+	p := &ProviderAssignment{}
+	for _, q := range []*ProviderAssignment{over, base} {
+		if q == nil {
+			continue
+		}
+		if p.Ambiguous == "" {
+			p.Ambiguous = q.Ambiguous
+		}
+		p.Assign = append(p.Assign, q.Assign...)
+	}
+	return p
+}
+
+// ProviderChain opts in to following providers through module calls.
+type ProviderChain struct {
+	// Mappings checks the providers = { … } of module calls against the child's
+	// configuration_aliases and required_providers, and against the caller's own aliases.
+	Mappings *bool `hcl:"mappings,optional" json:"mappings,omitempty"`
+	// References checks that provider = aliases on resources and data sources exist in their module.
+	References *bool `hcl:"references,optional" json:"references,omitempty"`
+}
+
+// MappingsEnabled reports whether provider_chain.mappings is on.
+func (p *ProviderChain) MappingsEnabled() bool {
+	return p != nil && p.Mappings != nil && *p.Mappings
+}
+
+// ReferencesEnabled reports whether provider_chain.references is on.
+func (p *ProviderChain) ReferencesEnabled() bool {
+	return p != nil && p.References != nil && *p.References
+}
+
+func mergeProviderChain(base, over *ProviderChain) *ProviderChain {
+	if base == nil && over == nil {
+		return nil
+	}
+	p := &ProviderChain{}
+	for _, q := range []*ProviderChain{base, over} {
+		if q == nil {
+			continue
+		}
+		if q.Mappings != nil {
+			p.Mappings = q.Mappings
+		}
+		if q.References != nil {
+			p.References = q.References
+		}
+	}
+	return p
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// RenamesFile is the name of the decisions file `tfsuit fix --interactive` keeps next to the code.
+const RenamesFile = ".tfsuit-renames.hcl"
+
+// RenameMap records explicit fix decisions keyed by Terraform address.
+// Keys may be prefixed with a module directory relative to the fix root ("modules/db:var.name").
+type RenameMap struct {
+	Renames   map[string]string `hcl:"renames,optional" json:"renames,omitempty"`
+	Files     map[string]string `hcl:"files,optional" json:"files,omitempty"`
+	Providers map[string]string `hcl:"providers,optional" json:"providers,omitempty"`
+}
+
+// LoadRenameMap reads a rename map; a missing file yields an empty map.
+func LoadRenameMap(path string) (*RenameMap, error) {
+	m := &RenameMap{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			m.init()
+			return m, nil
+		}
+		return nil, err
+	}
+	file, diags := hclsyntax.ParseConfig(data, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%s", diags.Error())
+	}
+	if diags := gohcl.DecodeBody(file.Body, nil, m); diags.HasErrors() {
+		return nil, fmt.Errorf("%s", diags.Error())
+	}
+	m.init()
+	return m, nil
+}
+
+func (m *RenameMap) init() {
+	if m.Renames == nil {
+		m.Renames = map[string]string{}
+	}
+	if m.Files == nil {
+		m.Files = map[string]string{}
+	}
+	if m.Providers == nil {
+		m.Providers = map[string]string{}
+	}
+}
+
+// Empty reports whether the map holds no decisions at all.
+func (m *RenameMap) Empty() bool {
+	return m == nil || len(m.Renames)+len(m.Files)+len(m.Providers) == 0
+}
+
+// Save writes the map as HCL with stable ordering.
+func (m *RenameMap) Save(path string) error {
+	var b strings.Builder
+	b.WriteString("# Decisions recorded by `tfsuit fix --interactive`.\n")
+	b.WriteString("# An entry mapping a name to itself (or a provider to \"\") means the change was skipped.\n")
+	writeStringMap(&b, "renames", m.Renames)
+	writeStringMap(&b, "files", m.Files)
+	writeStringMap(&b, "providers", m.Providers)
+	return ioutil.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+func writeStringMap(b *strings.Builder, name string, entries map[string]string) {
+	if len(entries) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(b, "\n%s = {\n", name)
+	for _, k := range keys {
+		fmt.Fprintf(b, "  %s = %s\n", strconv.Quote(k), strconv.Quote(entries[k]))
+	}
+	b.WriteString("}\n")
+}
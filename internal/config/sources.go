@@ -0,0 +1,35 @@
+package config
+
+import "path/filepath"
+
+// ModuleSources tells where registry and git modules live on disk; local paths and the
+// .terraform/modules/modules.json written by terraform init are always used.
+type ModuleSources struct {
+	// VendorDir, relative to the scanned root, holds modules laid out by address
+	// (terraform-aws-modules/vpc/aws, github.com/org/repo/subdir). It is not scanned.
+	VendorDir string `hcl:"vendor_dir,optional" json:"vendor_dir,omitempty"`
+}
+
+// Vendor returns the vendor directory for a scan of root, or "" when none is set.
+func (m *ModuleSources) Vendor(root string) string {
+	if m == nil || m.VendorDir == "" {
+		return ""
+	}
+	if filepath.IsAbs(m.VendorDir) {
+		return filepath.Clean(m.VendorDir)
+	}
+	return filepath.Join(root, m.VendorDir)
+}
+
+func mergeModuleSources(base, over *ModuleSources) *ModuleSources {
+	if base == nil && over == nil {
+		return nil
+	}
+	m := &ModuleSources{}
+	for _, p := range []*ModuleSources{base, over} {
+		if p != nil && p.VendorDir != "" {
+			m.VendorDir = p.VendorDir
+		}
+	}
+	return m
+}
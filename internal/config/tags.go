@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+)
+
+// TagsPolicy requires tags on taggable resources, in addition to naming rules.
+type TagsPolicy struct {
+	// Required keys, satisfied by the resource itself or by the default_tags of its provider.
+	Required []string `hcl:"required,optional" json:"required,omitempty"`
+	// KeyCase constrains every tag key: PascalCase, camelCase, snake_case or kebab-case.
+	KeyCase string `hcl:"key_case,optional" json:"key_case,omitempty"`
+	// Values maps tag keys to the pattern their literal values must match.
+	Values map[string]string `hcl:"values,optional" json:"values,omitempty"`
+	// Types are globs (aws_*) of the resource types that must carry tags even when they set
+	// none; resources that set tags are always checked.
+	Types        []string `hcl:"types,optional" json:"types,omitempty"`
+	ExcludeTypes []string `hcl:"exclude_types,optional" json:"exclude_types,omitempty"`
+	// Attribute holding the tags, "tags" by default (labels on Google Cloud).
+	Attribute string `hcl:"attribute,optional" json:"attribute,omitempty"`
+	// FromVariables lets fix insert missing keys as references: { Owner = "owner" } adds Owner = var.owner.
+	FromVariables map[string]string `hcl:"from_variables,optional" json:"from_variables,omitempty"`
+
+	keyRe    *regexp.Regexp
+	valueRes map[string]*regexp.Regexp
+}
+
+var keyCases = map[string]string{
+	"PascalCase": `^[A-Z][a-zA-Z0-9]*$`,
+	"camelCase":  `^[a-z][a-zA-Z0-9]*$`,
+	"snake_case": `^[a-z][a-z0-9]*(_[a-z0-9]+)*$`,
+	"kebab-case": `^[a-z][a-z0-9]*(-[a-z0-9]+)*$`,
+}
+
+func (t *TagsPolicy) init() error {
+	if t == nil {
+		return nil
+	}
+	t.keyRe = nil
+	if t.KeyCase != "" {
+		expr, ok := keyCases[t.KeyCase]
+		if !ok {
+			return fmt.Errorf("invalid tags.key_case '%s' (valid: PascalCase, camelCase, snake_case, kebab-case)", t.KeyCase)
+		}
+		t.keyRe = regexp.MustCompile(expr)
+	}
+	t.valueRes = map[string]*regexp.Regexp{}
+	for key, pattern := range t.Values {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid tags.values[%q] '%s': %w", key, pattern, err)
+		}
+		t.valueRes[key] = re
+	}
+	for _, glob := range append(append([]string{}, t.Types...), t.ExcludeTypes...) {
+		if _, err := path.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid tags type glob '%s': %w", glob, err)
+		}
+	}
+	return nil
+}
+
+// TagAttribute is the attribute holding the tags of a resource.
+func (t *TagsPolicy) TagAttribute() string {
+	if t.Attribute == "" {
+		return "tags"
+	}
+	return t.Attribute
+}
+
+// Requires reports whether resources of type typ must carry tags even when they set none.
+func (t *TagsPolicy) Requires(typ string) bool {
+	return matchGlobs(t.Types, typ) && !t.Excludes(typ)
+}
+
+// Excludes reports whether resources of type typ are left out of the policy.
+func (t *TagsPolicy) Excludes(typ string) bool {
+	return matchGlobs(t.ExcludeTypes, typ)
+}
+
+func matchGlobs(globs []string, typ string) bool {
+	for _, g := range globs {
+		if ok, _ := path.Match(g, typ); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyOK reports whether key follows key_case.
+func (t *TagsPolicy) KeyOK(key string) bool {
+	return t.keyRe == nil || t.keyRe.MatchString(key)
+}
+
+// ValueOK checks the value of tag key against its pattern, if any.
+func (t *TagsPolicy) ValueOK(key, value string) bool {
+	re, ok := t.valueRes[key]
+	return !ok || re.MatchString(value)
+}
+
+func mergeTags(base, over *TagsPolicy) *TagsPolicy {
+	if base == nil && over == nil {
+		return nil
+	}
+	t := &TagsPolicy{}
+	for _, p := range []*TagsPolicy{base, over} {
+		if p == nil {
+			continue
+		}
+		t.Required = union(t.Required, p.Required)
+		t.Types = union(t.Types, p.Types)
+		t.ExcludeTypes = union(t.ExcludeTypes, p.ExcludeTypes)
+		if p.KeyCase != "" {
+			t.KeyCase = p.KeyCase
+		}
+		if p.Attribute != "" {
+			t.Attribute = p.Attribute
+		}
+		t.Values = mergeMap(t.Values, p.Values)
+		t.FromVariables = mergeMap(t.FromVariables, p.FromVariables)
+	}
+	return t
+}
+
+func mergeMap(base, over map[string]string) map[string]string {
+	if len(over) == 0 {
+		return base
+	}
+	out := make(map[string]string, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range over {
+		out[k] = v
+	}
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
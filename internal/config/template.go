@@ -0,0 +1,292 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Segment constrains one {placeholder} of a naming template.
+type Segment struct {
+	Name    string   `hcl:"name,label" json:"name"`
+	Values  []string `hcl:"values,optional" json:"values,omitempty"`
+	Pattern string   `hcl:"pattern,optional" json:"pattern,omitempty"`
+	// Default fills the segment when fix can't find it in the current name.
+	Default string `hcl:"default,optional" json:"default,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// defaultSegmentPattern applies to placeholders without a segment block or constraints.
+const defaultSegmentPattern = `[a-z0-9]+`
+
+var placeholderRe = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// templatePart is either a literal separator or a segment of a template.
+type templatePart struct {
+	literal string
+	segment *Segment
+}
+
+func (s *Segment) expr() string {
+	if len(s.Values) > 0 {
+		quoted := make([]string, len(s.Values))
+		for i, v := range s.Values {
+			quoted[i] = regexp.QuoteMeta(v)
+		}
+		return "(?:" + strings.Join(quoted, "|") + ")"
+	}
+	if s.Pattern != "" {
+		return "(?:" + s.Pattern + ")"
+	}
+	return defaultSegmentPattern
+}
+
+func (s *Segment) describe() string {
+	if len(s.Values) > 0 {
+		return "one of " + strings.Join(s.Values, ", ")
+	}
+	if s.Pattern != "" {
+		return s.Pattern
+	}
+	return defaultSegmentPattern
+}
+
+func (s *Segment) matches(v string) bool {
+	return s.re.MatchString(v)
+}
+
+func (r *Rule) segment(name string) *Segment {
+	for _, s := range r.Segments {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// compileTemplate splits the template into literals and segments and builds its regexp.
+func (r *Rule) compileTemplate() error {
+	r.templateParts = nil
+	var expr strings.Builder
+	expr.WriteString("^")
+	last := 0
+	for _, loc := range placeholderRe.FindAllStringSubmatchIndex(r.Template, -1) {
+		if lit := r.Template[last:loc[0]]; lit != "" {
+			r.templateParts = append(r.templateParts, templatePart{literal: lit})
+			expr.WriteString(regexp.QuoteMeta(lit))
+		}
+		name := r.Template[loc[2]:loc[3]]
+		seg := r.segment(name)
+		if seg == nil {
+			seg = &Segment{Name: name}
+		}
+		re, err := regexp.Compile("^" + seg.expr() + "$")
+		if err != nil {
+			return fmt.Errorf("invalid segment %q: %w", name, err)
+		}
+		seg.re = re
+		r.templateParts = append(r.templateParts, templatePart{segment: seg})
+		expr.WriteString(seg.expr())
+		last = loc[1]
+	}
+	if lit := r.Template[last:]; lit != "" {
+		r.templateParts = append(r.templateParts, templatePart{literal: lit})
+		expr.WriteString(regexp.QuoteMeta(lit))
+	}
+	expr.WriteString("$")
+	if len(r.templateParts) == 0 {
+		return fmt.Errorf("template %q has no {segments}", r.Template)
+	}
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return fmt.Errorf("invalid template %q: %w", r.Template, err)
+	}
+	r.templateRe = re
+	return nil
+}
+
+// Explain describes why name breaks the rule, naming the template segment that failed.
+func (r *Rule) Explain(kind, name string) string {
+	if r.patternRe != nil && !r.patternRe.MatchString(name) {
+		return fmt.Sprintf("%s '%s' does not match pattern %s", kind, name, r.Pattern)
+	}
+	if r.templateRe == nil || r.templateRe.MatchString(name) {
+		return ""
+	}
+	prefix := fmt.Sprintf("%s '%s' does not match template %s", kind, name, r.Template)
+	rest := name
+	for i, part := range r.templateParts {
+		if part.segment == nil {
+			if !strings.HasPrefix(rest, part.literal) {
+				return fmt.Sprintf("%s: expected %q after %s", prefix, part.literal, r.describeUpTo(i))
+			}
+			rest = rest[len(part.literal):]
+			continue
+		}
+		value, remaining := r.takeSegment(i, rest)
+		seg := part.segment
+		if value == "" {
+			return fmt.Sprintf("%s: segment {%s} is missing", prefix, seg.Name)
+		}
+		if !seg.matches(value) {
+			return fmt.Sprintf("%s: segment {%s} is '%s', expected %s", prefix, seg.Name, value, seg.describe())
+		}
+		rest = remaining
+	}
+	if rest != "" {
+		return fmt.Sprintf("%s: unexpected trailing '%s'", prefix, rest)
+	}
+	return prefix
+}
+
+func (r *Rule) describeUpTo(i int) string {
+	for j := i - 1; j >= 0; j-- {
+		if seg := r.templateParts[j].segment; seg != nil {
+			return "{" + seg.Name + "}"
+		}
+	}
+	return "the start"
+}
+
+// takeSegment cuts the value of segment part i from the start of rest: a known value when the
+// segment lists values, otherwise everything up to the next literal (or the end).
+func (r *Rule) takeSegment(i int, rest string) (string, string) {
+	next := ""
+	if i+1 < len(r.templateParts) {
+		next = r.templateParts[i+1].literal
+	}
+	seg := r.templateParts[i].segment
+	best := -1
+	for _, v := range seg.Values {
+		if strings.HasPrefix(rest, v) && len(v) > best && (next == "" || strings.HasPrefix(rest[len(v):], next)) {
+			best = len(v)
+		}
+	}
+	if best >= 0 {
+		return rest[:best], rest[best:]
+	}
+	if next == "" {
+		return rest, ""
+	}
+	if idx := strings.Index(rest, next); idx >= 0 {
+		return rest[:idx], rest[idx:]
+	}
+	return rest, ""
+}
+
+var wordSplitRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// SuggestName tries to turn name into one that fits the template: words that are known values of
+// a segment move to that segment, the remaining words fill the other segments in order, and
+// segments still missing are inferred from the directories in path or their default.
+func (r *Rule) SuggestName(name, path string) (string, bool) {
+	if r.templateRe == nil {
+		return "", false
+	}
+	var words []string
+	for _, w := range wordSplitRe.Split(strings.ToLower(name), -1) {
+		if w != "" {
+			words = append(words, w)
+		}
+	}
+
+	var segs []*Segment
+	for _, part := range r.templateParts {
+		if part.segment != nil {
+			segs = append(segs, part.segment)
+		}
+	}
+	values := make([]string, len(segs))
+	used := make([]bool, len(words))
+
+	// 1. enumerated segments claim their values wherever they appear
+	for i, seg := range segs {
+		if len(seg.Values) == 0 {
+			continue
+		}
+		for j, w := range words {
+			if !used[j] && seg.matches(w) {
+				values[i], used[j] = w, true
+				break
+			}
+		}
+	}
+
+	// 2. the other words fill the free segments in order; extra words join the last one
+	var rest []string
+	for j, w := range words {
+		if !used[j] {
+			rest = append(rest, w)
+		}
+	}
+	var free []int
+	for i, seg := range segs {
+		if len(seg.Values) == 0 {
+			free = append(free, i)
+		}
+	}
+	for k, i := range free {
+		if len(rest) == 0 {
+			break
+		}
+		if k == len(free)-1 {
+			values[i] = strings.Join(rest, r.joiner())
+			rest = nil
+			break
+		}
+		values[i], rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 {
+		return "", false
+	}
+
+	// 3. infer what is still missing
+	dirs := strings.Split(strings.ToLower(filepath.ToSlash(filepath.Dir(path))), "/")
+	for i, seg := range segs {
+		if values[i] != "" {
+			continue
+		}
+		for _, d := range dirs {
+			if len(seg.Values) > 0 && seg.matches(d) {
+				values[i] = d
+			}
+		}
+		if values[i] == "" {
+			values[i] = seg.Default
+		}
+		if values[i] == "" && len(seg.Values) == 1 {
+			values[i] = seg.Values[0]
+		}
+		if values[i] == "" {
+			return "", false
+		}
+	}
+
+	var out strings.Builder
+	k := 0
+	for _, part := range r.templateParts {
+		if part.segment == nil {
+			out.WriteString(part.literal)
+			continue
+		}
+		out.WriteString(values[k])
+		k++
+	}
+	suggestion := out.String()
+	if !r.Matches(suggestion) {
+		return "", false
+	}
+	return suggestion, true
+}
+
+// joiner is the first literal of the template, used to glue extra words into one segment.
+func (r *Rule) joiner() string {
+	for _, part := range r.templateParts {
+		if part.segment == nil {
+			return part.literal
+		}
+	}
+	return "_"
+}
@@ -0,0 +1,44 @@
+package config
+
+// UnusedCheck opts in to reporting declarations never referenced within their module.
+type UnusedCheck struct {
+	Variables *bool `hcl:"variables,optional" json:"variables,omitempty"`
+	Locals    *bool `hcl:"locals,optional" json:"locals,omitempty"`
+	Data      *bool `hcl:"data,optional" json:"data,omitempty"`
+}
+
+// Kinds returns the enabled declaration kinds (variable, local, data).
+func (u *UnusedCheck) Kinds() map[string]bool {
+	kinds := map[string]bool{}
+	if u == nil {
+		return kinds
+	}
+	for kind, on := range map[string]*bool{"variable": u.Variables, "local": u.Locals, "data": u.Data} {
+		if on != nil && *on {
+			kinds[kind] = true
+		}
+	}
+	return kinds
+}
+
+func mergeUnused(base, over *UnusedCheck) *UnusedCheck {
+	if base == nil && over == nil {
+		return nil
+	}
+	u := &UnusedCheck{}
+	for _, p := range []*UnusedCheck{base, over} {
+		if p == nil {
+			continue
+		}
+		if p.Variables != nil {
+			u.Variables = p.Variables
+		}
+		if p.Locals != nil {
+			u.Locals = p.Locals
+		}
+		if p.Data != nil {
+			u.Data = p.Data
+		}
+	}
+	return u
+}
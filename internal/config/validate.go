@@ -0,0 +1,325 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp/syntax"
+	"strings"
+)
+
+// Kinds are the block kinds rules and ordering.sort refer to.
+var Kinds = []string{"variable", "output", "module", "resource", "data"}
+
+// BlockKinds are the top-level block types block_spacing.allow_compact refers to.
+var BlockKinds = append(append([]string{}, Kinds...), "locals", "provider", "terraform", "moved", "import", "removed", "check")
+
+// Issue is a problem found by Validate; warnings don't make the config unusable.
+type Issue struct {
+	Warning bool
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Warning {
+		return "warning: " + i.Message
+	}
+	return "error: " + i.Message
+}
+
+// Validate checks what Load accepts but is most likely a mistake. names holds the labels found
+// in the tree per kind (plus "file" for file names) and is used to spot ignore entries that match
+// nothing; pass nil to skip that check.
+func (c *Config) Validate(names map[string][]string) []Issue {
+	var issues []Issue
+
+	known := map[string]bool{}
+	for _, k := range BlockKinds {
+		known[k] = true
+	}
+	if c.Spacing != nil {
+		for _, kind := range c.Spacing.AllowCompact {
+			if k := strings.ToLower(strings.TrimSpace(kind)); !known[k] {
+				issues = append(issues, Issue{Message: fmt.Sprintf("block_spacing.allow_compact: unknown kind %q (valid: %s)",
+					kind, strings.Join(BlockKinds, ", "))})
+			}
+		}
+	}
+
+	for _, nr := range c.namedRules() {
+		r := nr.rule
+		if r == nil {
+			continue
+		}
+		for _, seg := range r.Segments {
+			if !strings.Contains(r.Template, "{"+seg.Name+"}") {
+				issues = append(issues, Issue{Warning: true, Message: fmt.Sprintf("%s.segment %q is not used in template %q", nr.block, seg.Name, r.Template)})
+			}
+		}
+		if neverMatches(r.Pattern) {
+			issues = append(issues, Issue{Warning: true, Message: fmt.Sprintf("%s.pattern %q can never match a name", nr.block, r.Pattern)})
+		}
+		for _, ig := range r.IgnoreRegex {
+			if neverMatches(ig) {
+				issues = append(issues, Issue{Warning: true, Message: fmt.Sprintf("%s.ignore_regex %q can never match a name", nr.block, ig)})
+			}
+		}
+		if names == nil {
+			continue
+		}
+		present := names[nr.kind]
+		for _, ex := range r.IgnoreExact {
+			if !contains(present, ex) {
+				issues = append(issues, Issue{Warning: true, Message: fmt.Sprintf("%s.ignore_exact %q matches nothing in the tree", nr.block, ex)})
+			}
+		}
+		for i, ig := range r.IgnoreRegex {
+			if i >= len(r.ignoreReList) {
+				break
+			}
+			re := r.ignoreReList[i]
+			hit := false
+			for _, n := range present {
+				if re.MatchString(n) {
+					hit = true
+					break
+				}
+			}
+			if !hit {
+				issues = append(issues, Issue{Warning: true, Message: fmt.Sprintf("%s.ignore_regex %q matches nothing in the tree", nr.block, ig)})
+			}
+		}
+	}
+
+	for _, k := range sortedKeys(c.Renames) {
+		if c.Renames[k] == "" {
+			issues = append(issues, Issue{Message: fmt.Sprintf("renames[%q] is empty", k)})
+		}
+	}
+
+	if t := c.Tags; t != nil {
+		for _, k := range sortedKeys(t.FromVariables) {
+			if !contains(t.Required, k) {
+				issues = append(issues, Issue{Warning: true, Message: fmt.Sprintf("tags.from_variables[%q] is not a required tag", k)})
+			}
+		}
+		for _, k := range t.Required {
+			if !t.KeyOK(k) {
+				issues = append(issues, Issue{Warning: true, Message: fmt.Sprintf("tags.required %q is not %s", k, t.KeyCase)})
+			}
+		}
+	}
+	if p := c.ProviderAssignment; p != nil {
+		for _, a := range p.Assign {
+			if len(a.Files) == 0 && len(a.Directories) == 0 && len(a.Types) == 0 {
+				issues = append(issues, Issue{Warning: true, Message: fmt.Sprintf("provider_assignment.assign %q has no files, directories or types and matches nothing", a.Alias)})
+			}
+		}
+	}
+	if l := c.Layout; l != nil {
+		for _, k := range l.kinds() {
+			if len(*k.files) > 0 && l.Target(k.kind) == "" {
+				issues = append(issues, Issue{Warning: true, Message: fmt.Sprintf("layout.%s has no plain file name, fix cannot move misplaced blocks", k.kind)})
+			}
+		}
+	}
+	return issues
+}
+
+// ValidateFile checks the config file at path on its own, without its preset, the files it
+// extends or the configs it is layered on, so each issue is reported once, against the file that
+// declares it. Every compile error is reported, not only the first; names is as for Validate.
+func ValidateFile(path string, names map[string][]string) []Issue {
+	layer, err := decodeFile(path)
+	if err != nil {
+		return []Issue{{Message: err.Error()}}
+	}
+	var issues []Issue
+	if filepath.Ext(path) == ".json" {
+		issues = append(issues, unknownJSONFields(path)...)
+	}
+	if layer.Preset != "" {
+		if _, err := presetLayer(layer.Preset); err != nil {
+			issues = append(issues, Issue{Message: err.Error()})
+		}
+	}
+	for _, err := range layer.compileParts() {
+		issues = append(issues, Issue{Message: err.Error()})
+	}
+	return append(issues, layer.Validate(names)...)
+}
+
+// unknownJSONFields warns about keys Load ignores in a JSON config, usually typos.
+func unknownJSONFields(path string) []Issue {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var cfg Config
+	if err := dec.Decode(&cfg); err != nil && strings.HasPrefix(err.Error(), "json: unknown field") {
+		return []Issue{{Warning: true, Message: fmt.Sprintf("%s: %s, it is ignored", path, strings.TrimPrefix(err.Error(), "json: "))}}
+	}
+	return nil
+}
+
+type namedRule struct {
+	block string // config block name
+	kind  string // kind of the names it applies to
+	rule  *Rule
+}
+
+func (c *Config) namedRules() []namedRule {
+	return []namedRule{
+		{"variables", "variable", c.Variables},
+		{"outputs", "output", c.Outputs},
+		{"modules", "module", c.Modules},
+		{"resources", "resource", c.Resources},
+		{"data", "data", c.Data},
+		{"checks", "check", c.Checks},
+		{"instance_keys", "instance_key", c.InstanceKeys},
+		{"files", "file", c.Files},
+	}
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// neverMatches reports patterns that cannot match any non-empty name, such as `^$` or `a^b`.
+func neverMatches(pattern string) bool {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return false // compile errors are reported by Load
+	}
+	re = re.Simplify()
+	m := analyze(re)
+	if !m.possible {
+		return true
+	}
+	// an empty match satisfies any name, unless it is pinned to both ends of the text
+	return !m.nonEmpty && m.begin && m.end
+}
+
+type matchInfo struct {
+	possible bool // some input matches
+	nonEmpty bool // some match consumes at least one character
+	begin    bool // every match is anchored at the start of the text
+	end      bool // every match is anchored at the end of the text
+}
+
+func analyze(re *syntax.Regexp) matchInfo {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return matchInfo{}
+	case syntax.OpEmptyMatch, syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return matchInfo{possible: true}
+	case syntax.OpBeginLine, syntax.OpBeginText:
+		return matchInfo{possible: true, begin: true}
+	case syntax.OpEndLine, syntax.OpEndText:
+		return matchInfo{possible: true, end: true}
+	case syntax.OpLiteral:
+		return matchInfo{possible: true, nonEmpty: len(re.Rune) > 0}
+	case syntax.OpCharClass:
+		return matchInfo{possible: len(re.Rune) > 0, nonEmpty: len(re.Rune) > 0}
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return matchInfo{possible: true, nonEmpty: true}
+	case syntax.OpCapture:
+		return analyze(re.Sub[0])
+	case syntax.OpStar, syntax.OpQuest:
+		sub := analyze(re.Sub[0])
+		return matchInfo{possible: true, nonEmpty: sub.possible && sub.nonEmpty}
+	case syntax.OpPlus:
+		return analyze(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min == 0 {
+			sub := analyze(re.Sub[0])
+			return matchInfo{possible: true, nonEmpty: re.Max != 0 && sub.possible && sub.nonEmpty}
+		}
+		return analyze(re.Sub[0])
+	case syntax.OpConcat:
+		out := matchInfo{possible: true}
+		consumed, ended := false, false
+		for _, sub := range re.Sub {
+			m := analyze(sub)
+			if !m.possible {
+				return matchInfo{}
+			}
+			// nothing can be consumed before a start anchor or after an end anchor
+			if (isAnchor(sub, true) && consumed) || (mustConsume(sub) && ended) {
+				return matchInfo{}
+			}
+			if isAnchor(sub, false) {
+				ended = true
+			}
+			if mustConsume(sub) {
+				consumed = true
+			}
+			out.nonEmpty = out.nonEmpty || m.nonEmpty
+			out.begin = out.begin || m.begin
+			out.end = out.end || m.end
+		}
+		return out
+	case syntax.OpAlternate:
+		out := matchInfo{begin: true, end: true}
+		for _, sub := range re.Sub {
+			m := analyze(sub)
+			if !m.possible {
+				continue
+			}
+			out.possible = true
+			out.nonEmpty = out.nonEmpty || m.nonEmpty
+			out.begin = out.begin && m.begin
+			out.end = out.end && m.end
+		}
+		if !out.possible {
+			return matchInfo{}
+		}
+		return out
+	}
+	return matchInfo{possible: true, nonEmpty: true}
+}
+
+func isAnchor(re *syntax.Regexp, begin bool) bool {
+	if begin {
+		return re.Op == syntax.OpBeginText || re.Op == syntax.OpBeginLine
+	}
+	return re.Op == syntax.OpEndText || re.Op == syntax.OpEndLine
+}
+
+// mustConsume reports whether every match of re consumes at least one character.
+func mustConsume(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return len(re.Rune) > 0
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return true
+	case syntax.OpCapture, syntax.OpPlus:
+		return mustConsume(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min > 0 && mustConsume(re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if mustConsume(sub) {
+				return true
+			}
+		}
+		return false
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if !mustConsume(sub) {
+				return false
+			}
+		}
+		return len(re.Sub) > 0
+	}
+	return false
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/josdagaro/tfsuit/internal/cache"
 	"github.com/josdagaro/tfsuit/internal/config"
 	"github.com/josdagaro/tfsuit/internal/model"
+	"github.com/josdagaro/tfsuit/internal/modules"
 	"github.com/josdagaro/tfsuit/internal/parser"
 )
 
@@ -21,6 +22,24 @@ import (
 type ScanStats struct {
 	Files    int
 	Duration time.Duration
+	Config   string   // archivo de configuración base usado
+	Layers   []string // todos los archivos de config aplicados (base, extends y anidados), del más externo al más interno
+}
+
+// describeConfig nombra la config base y, si hay más capas, las demás relativas a su directorio.
+func (s *ScanStats) describeConfig() string {
+	base, _ := filepath.Abs(s.Config)
+	out := s.Config
+	for _, l := range s.Layers {
+		if l == base {
+			continue
+		}
+		if rel, err := filepath.Rel(filepath.Dir(base), l); err == nil && !strings.HasPrefix(rel, "..") {
+			l = rel
+		}
+		out += " + " + l
+	}
+	return out
 }
 
 // Scan recorre el dir, parsea concurrentemente, usa caché y devuelve hallazgos + estadísticas.
@@ -31,7 +50,30 @@ func Scan(dir string, cfg *config.Config) ([]model.Finding, ScanStats, error) {
 	if err != nil {
 		return nil, ScanStats{}, err
 	}
-	stats := ScanStats{Files: len(files)}
+	// módulos vendorizados: se leen para resolver llamadas, pero no se escanean
+	res := modules.NewResolver(dir, cfg.ModuleSources.Vendor(dir))
+	files = res.WithoutVendored(files)
+	stats := ScanStats{Files: len(files), Config: cfg.Path()}
+
+	// Config efectiva por archivo: tfsuit.hcl anidados sobreescriben la base en su subárbol
+	tree := config.NewTree(dir, cfg)
+	fileCfg := make(map[string]*config.Config, len(files))
+	for _, f := range files {
+		fc, err := tree.For(filepath.Dir(f))
+		if err != nil {
+			return nil, ScanStats{}, err
+		}
+		fileCfg[f] = fc
+	}
+	seen := map[string]bool{}
+	for _, d := range append([]string{dir}, filesDirs(files)...) {
+		for _, l := range tree.Layers(d) {
+			if !seen[l] {
+				seen[l] = true
+				stats.Layers = append(stats.Layers, l)
+			}
+		}
+	}
 
 	// Carga caché previo
 	c, _ := cache.Load(dir)
@@ -61,7 +103,7 @@ func Scan(dir string, cfg *config.Config) ([]model.Finding, ScanStats, error) {
 				hash := cache.Hash(content)
 
 				// Parsea archivo
-				res, err := parser.ParseFile(path, cfg)
+				res, err := parser.ParseFile(path, fileCfg[path])
 				if err == nil {
 					findingsCh <- res
 				}
@@ -90,15 +132,29 @@ func Scan(dir string, cfg *config.Config) ([]model.Finding, ScanStats, error) {
 
 	var all []model.Finding
 
-	if cfg.Files != nil {
-		if fileFindings := validateFilenames(files, cfg.Files); len(fileFindings) > 0 {
-			all = append(all, fileFindings...)
-		}
+	if fileFindings := validateFilenames(files, fileCfg); len(fileFindings) > 0 {
+		all = append(all, fileFindings...)
 	}
 	for batch := range findingsCh {
 		all = append(all, batch...)
 	}
 
+	// tags y declaraciones sin uso necesitan todo el árbol (default_tags heredados, índice por módulo)
+	tagFindings, _ := parser.CheckTags(files, fileCfg)
+	all = append(all, tagFindings...)
+	all = append(all, parser.CheckProviders(files, fileCfg, res)...)
+	all = append(all, parser.CheckModuleCalls(files, res)...)
+	targets, err := parser.CheckTargets(files, res)
+	if err != nil {
+		return nil, ScanStats{}, err
+	}
+	all = append(all, targets...)
+	unused, err := parser.CheckUnused(files, fileCfg)
+	if err != nil {
+		return nil, ScanStats{}, err
+	}
+	all = append(all, unused...)
+
 	// Guarda caché (una sola vez, ya en secuencia)
 	_ = c.Save(dir)
 
@@ -106,9 +162,27 @@ func Scan(dir string, cfg *config.Config) ([]model.Finding, ScanStats, error) {
 	return all, stats, nil
 }
 
-func validateFilenames(files []string, rule *config.Rule) []model.Finding {
+// filesDirs devuelve los directorios de files, ordenados y sin repetir.
+func filesDirs(files []string) []string {
+	seen := map[string]bool{}
+	var dirs []string
+	for _, f := range files {
+		if d := filepath.Dir(f); !seen[d] {
+			seen[d] = true
+			dirs = append(dirs, d)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+func validateFilenames(files []string, cfgs map[string]*config.Config) []model.Finding {
 	var findings []model.Finding
 	for _, path := range files {
+		rule := cfgs[path].Files
+		if rule == nil {
+			continue
+		}
 		name := filepath.Base(path)
 		if rule.IsIgnored(name) || rule.Matches(name) {
 			continue
@@ -116,6 +190,7 @@ func validateFilenames(files []string, rule *config.Rule) []model.Finding {
 		findings = append(findings, model.Finding{
 			File:    path,
 			Line:    1,
+			Column:  1,
 			Kind:    "file",
 			Name:    name,
 			Message: fmt.Sprintf("file '%s' does not match pattern %s", name, rule.Pattern),
@@ -124,8 +199,29 @@ func validateFilenames(files []string, rule *config.Rule) []model.Finding {
 	return findings
 }
 
+// kindLabels nombra cada tipo de hallazgo en el resumen, en singular y plural, en orden de salida.
+var kindLabels = []struct{ kind, one, many string }{
+	{"file", "file", "files"},
+	{"variable", "variable", "variables"},
+	{"output", "output", "outputs"},
+	{"module", "module", "modules"},
+	{"data", "data source", "data sources"},
+	{"resource", "resource", "resources"},
+	{"check", "check", "checks"},
+	{"attribute", "attribute", "attributes"},
+	{"tags", "tag issue", "tag issues"},
+	{"provider", "provider", "providers"},
+	{"layout", "misplaced block", "misplaced blocks"},
+	{"ordering", "ordering issue", "ordering issues"},
+	{"spacing", "spacing issue", "spacing issues"},
+	{"moved", "moved block", "moved blocks"},
+	{"import", "import block", "import blocks"},
+	{"instance_key", "instance key", "instance keys"},
+	{"unused", "unused declaration", "unused declarations"},
+}
+
 // Format serializa hallazgos según el formato.
-// Modos: "pretty" (default), "json", "sarif".
+// Modos: "pretty" (default), "json" (arreglo de hallazgos), "json-v2" (con config usada), "sarif".
 func Format(f []model.Finding, mode string, stats *ScanStats) string {
 	switch mode {
 
@@ -133,10 +229,32 @@ func Format(f []model.Finding, mode string, stats *ScanStats) string {
 		b, _ := json.MarshalIndent(f, "", "  ")
 		return string(b) + "\n"
 
+	case "json-v2":
+		// sobre versionado: los mismos hallazgos más los metadatos del escaneo
+		report := struct {
+			Version  int             `json:"version"`
+			Config   string          `json:"config,omitempty"`
+			Layers   []string        `json:"layers,omitempty"`
+			Findings []model.Finding `json:"findings"`
+		}{Version: 2, Findings: f}
+		if report.Findings == nil {
+			report.Findings = []model.Finding{}
+		}
+		if stats != nil {
+			report.Config, report.Layers = stats.Config, stats.Layers
+		}
+		b, _ := json.MarshalIndent(report, "", "  ")
+		return string(b) + "\n"
+
 	case "sarif":
-		return buildSARIF(f) + "\n"
+		return buildSARIF(f, stats) + "\n"
 
 	default: // pretty
+		var header string
+		if stats != nil && stats.Config != "" {
+			header = fmt.Sprintf("⚙️  config: %s\n", stats.describeConfig())
+		}
+
 		// Resumen cuando NO hay violaciones
 		if len(f) == 0 {
 			if stats != nil {
@@ -145,7 +263,7 @@ func Format(f []model.Finding, mode string, stats *ScanStats) string {
 				if stats.Files == 1 {
 					word = "file"
 				}
-				return fmt.Sprintf("✅ No naming violations found — scanned %d %s in %s\n",
+				return header + fmt.Sprintf("✅ No naming violations found — scanned %d %s in %s\n",
 					stats.Files, word, d)
 			}
 			return "✅ No naming violations found\n"
@@ -169,10 +287,14 @@ func Format(f []model.Finding, mode string, stats *ScanStats) string {
 
 		// Impresión de violaciones
 		var sb strings.Builder
+		sb.WriteString(header)
 		sb.WriteString("\n❌ Violations:\n")
 		for _, v := range f {
-			fmt.Fprintf(&sb, "%s:%d [%s] %s\n",
-				v.File, v.Line, v.Kind, v.Message)
+			loc := fmt.Sprintf("%s:%d", v.File, v.Line)
+			if v.Column > 0 {
+				loc += fmt.Sprintf(":%d", v.Column)
+			}
+			fmt.Fprintf(&sb, "%s [%s] %s\n", loc, v.Kind, v.Message)
 		}
 
 		// Resumen al final (cuando SÍ hay violaciones)
@@ -180,13 +302,12 @@ func Format(f []model.Finding, mode string, stats *ScanStats) string {
 			d := stats.Duration.Truncate(10 * time.Millisecond)
 
 			// Desglose por tipo en orden legible
-			order := []string{"file", "variable", "output", "module", "data", "resource"}
 			var parts []string
-			for _, k := range order {
-				if n := byKind[k]; n > 0 {
-					name := k
-					if n != 1 {
-						name += "s"
+			for _, l := range kindLabels {
+				if n := byKind[l.kind]; n > 0 {
+					name := l.many
+					if n == 1 {
+						name = l.one
 					}
 					parts = append(parts, fmt.Sprintf("%d %s", n, name))
 				}
@@ -209,13 +330,16 @@ func Format(f []model.Finding, mode string, stats *ScanStats) string {
 }
 
 // buildSARIF construye un SARIF v2.1.0 mínimo.
-func buildSARIF(findings []model.Finding) string {
+func buildSARIF(findings []model.Finding, stats *ScanStats) string {
 	type (
 		artifactLocation struct {
 			Uri string `json:"uri"`
 		}
 		region struct {
-			StartLine int `json:"startLine"`
+			StartLine   int `json:"startLine"`
+			StartColumn int `json:"startColumn,omitempty"`
+			EndLine     int `json:"endLine,omitempty"`
+			EndColumn   int `json:"endColumn,omitempty"`
 		}
 		physicalLocation struct {
 			ArtifactLocation artifactLocation `json:"artifactLocation"`
@@ -253,6 +377,15 @@ func buildSARIF(findings []model.Finding) string {
 		}},
 	}
 
+	if stats != nil && stats.Config != "" {
+		run := sarif["runs"].([]interface{})[0].(map[string]interface{})
+		props := map[string]interface{}{"config": stats.Config}
+		if len(stats.Layers) > 0 {
+			props["layers"] = stats.Layers
+		}
+		run["properties"] = props
+	}
+
 	var results []result
 	for _, v := range findings {
 		results = append(results, result{
@@ -261,7 +394,7 @@ func buildSARIF(findings []model.Finding) string {
 			Locations: []location{{
 				PhysicalLocation: physicalLocation{
 					ArtifactLocation: artifactLocation{Uri: v.File},
-					Region:           region{StartLine: v.Line},
+					Region:           region{StartLine: v.Line, StartColumn: v.Column, EndLine: v.EndLine, EndColumn: v.EndColumn},
 				},
 			}},
 		})
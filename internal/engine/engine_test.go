@@ -16,12 +16,38 @@ func samplePath(parts ...string) string {
 	return filepath.Join(all...)
 }
 
+// copySample copies a sample into a temp dir, so the scan cache is not written into the repo.
+func copySample(t *testing.T, name string) string {
+	t.Helper()
+	dir := t.TempDir()
+	src := samplePath("samples", name)
+	err := filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, p)
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dir, rel), 0o755)
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, rel), b, info.Mode())
+	})
+	if err != nil {
+		t.Fatalf("copy sample: %v", err)
+	}
+	return dir
+}
+
 func TestScanSamplesSimple(t *testing.T) {
-	cfg, err := config.Load(samplePath("samples", "simple", "tfsuit.hcl"))
+	dir := copySample(t, "simple")
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
 	if err != nil {
 		t.Fatalf("load config: %v", err)
 	}
-	findings, stats, err := Scan(samplePath("samples", "simple"), cfg)
+	findings, stats, err := Scan(dir, cfg)
 	if err != nil {
 		t.Fatalf("Scan: %v", err)
 	}
@@ -33,6 +59,40 @@ func TestScanSamplesSimple(t *testing.T) {
 	}
 }
 
+func TestScanUsesNestedConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	write("tfsuit.hcl", `
+variables { pattern = "^[a-z_]+$" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`)
+	write(filepath.Join("legacy", "tfsuit.hcl"), `variables { ignore_exact = ["LegacyName"] }`)
+	write("main.tf", `variable "LegacyName" {}`+"\n")
+	write(filepath.Join("legacy", "main.tf"), `variable "LegacyName" {}`+"\n")
+
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	findings, _, err := Scan(dir, cfg)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(findings) != 1 || findings[0].File != filepath.Join(dir, "main.tf") {
+		t.Fatalf("expected a single finding outside legacy/, got %+v", findings)
+	}
+}
+
 func TestFormatModes(t *testing.T) {
 	findings := []model.Finding{{
 		File:    "main.tf",
@@ -41,18 +101,40 @@ func TestFormatModes(t *testing.T) {
 		Name:    "bad",
 		Message: "broken",
 	}}
-	stats := &ScanStats{Files: 1, Duration: time.Second}
+	stats := &ScanStats{Files: 1, Duration: time.Second, Config: "tfsuit.hcl"}
 
-	if out := Format(findings, "json", stats); !strings.Contains(out, `"file"`) {
-		t.Fatalf("json output missing fields: %s", out)
+	if out := Format(findings, "json", stats); !strings.HasPrefix(out, "[") || !strings.Contains(out, `"file"`) {
+		t.Fatalf("json output should be an array of findings: %s", out)
+	}
+	if out := Format(findings, "json-v2", stats); !strings.Contains(out, `"version": 2`) || !strings.Contains(out, `"config": "tfsuit.hcl"`) || !strings.Contains(out, `"findings": [`) {
+		t.Fatalf("json-v2 output missing fields: %s", out)
 	}
-	if out := Format(findings, "sarif", stats); !strings.Contains(out, `"results"`) {
+	if out := Format(findings, "sarif", stats); !strings.Contains(out, `"results"`) || !strings.Contains(out, `"config": "tfsuit.hcl"`) {
 		t.Fatalf("sarif output malformed: %s", out)
 	}
 	pretty := Format(findings, "pretty", stats)
-	if !strings.Contains(pretty, "Violations:") {
+	if !strings.Contains(pretty, "Violations:") || !strings.Contains(pretty, "config: tfsuit.hcl") {
 		t.Fatalf("pretty output missing violations: %s", pretty)
 	}
+	summary := Format([]model.Finding{
+		{File: "a.tf", Line: 1, Kind: "tags"},
+		{File: "a.tf", Line: 2, Kind: "tags"},
+		{File: "a.tf", Line: 3, Kind: "spacing"},
+		{File: "a.tf", Line: 4, Kind: "instance_key"},
+		{File: "a.tf", Line: 5, Kind: "unused"},
+	}, "pretty", stats)
+	if !strings.Contains(summary, "(2 tag issues, 1 spacing issue, 1 instance key, 1 unused declaration)") {
+		t.Fatalf("summary breakdown mismatch: %s", summary)
+	}
+	layered := &ScanStats{Files: 1, Config: "tfsuit.hcl"}
+	base, _ := filepath.Abs("tfsuit.hcl")
+	layered.Layers = []string{base, filepath.Join(filepath.Dir(base), "legacy", "tfsuit.hcl")}
+	if out := Format(findings, "pretty", layered); !strings.Contains(out, "config: tfsuit.hcl + "+filepath.Join("legacy", "tfsuit.hcl")) {
+		t.Fatalf("pretty header should list the layered configs: %s", out)
+	}
+	if out := Format(findings, "json-v2", layered); !strings.Contains(out, `"layers": [`) {
+		t.Fatalf("json-v2 output missing layers: %s", out)
+	}
 	empty := Format(nil, "pretty", stats)
 	if !strings.Contains(empty, "No naming violations") {
 		t.Fatalf("pretty empty message missing: %s", empty)
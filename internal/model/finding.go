@@ -6,4 +6,10 @@ type Finding struct {
 	Kind    string `json:"kind"`
 	Name    string `json:"name"`
 	Message string `json:"message"`
+
+	// Column is where the name label (or the offending value) starts; EndLine and EndColumn close
+	// the range when it is known (attribute values, instance keys).
+	Column    int `json:"column,omitempty"`
+	EndLine   int `json:"end_line,omitempty"`
+	EndColumn int `json:"end_column,omitempty"`
 }
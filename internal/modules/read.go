@@ -0,0 +1,119 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Interface is what a module exposes to its callers.
+type Interface struct {
+	Dir   string
+	Files int // .tf files parsed; without any, nothing is known about the module
+	// Variables maps each input to whether it is required (no default).
+	Variables map[string]bool
+	Outputs   map[string]bool
+	// Providers are the required_providers local names; Aliases their configuration_aliases.
+	Providers map[string]bool
+	Aliases   []string
+}
+
+// Read parses the .tf files directly in dir; unparsable files are skipped.
+func Read(dir string) (*Interface, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	mi := &Interface{Dir: dir, Variables: map[string]bool{}, Outputs: map[string]bool{}, Providers: map[string]bool{}}
+	aliases := map[string]bool{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".tf") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			continue
+		}
+		mi.Files++
+		for _, b := range file.Body.(*hclsyntax.Body).Blocks {
+			switch b.Type {
+			case "variable":
+				if len(b.Labels) == 1 {
+					_, hasDefault := b.Body.Attributes["default"]
+					mi.Variables[b.Labels[0]] = !hasDefault
+				}
+			case "output":
+				if len(b.Labels) == 1 {
+					mi.Outputs[b.Labels[0]] = true
+				}
+			case "terraform":
+				for _, rp := range b.Body.Blocks {
+					if rp.Type != "required_providers" {
+						continue
+					}
+					for name, attr := range rp.Body.Attributes {
+						mi.Providers[name] = true
+						for _, ref := range ConfigurationAliases(attr.Expr) {
+							aliases[ref] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	for ref := range aliases {
+		mi.Aliases = append(mi.Aliases, ref)
+	}
+	sort.Strings(mi.Aliases)
+	return mi, nil
+}
+
+// ConfigurationAliases reads configuration_aliases from a required_providers entry.
+func ConfigurationAliases(expr hclsyntax.Expression) []string {
+	obj, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, item := range obj.Items {
+		if hcl.ExprAsKeyword(item.KeyExpr) != "configuration_aliases" {
+			continue
+		}
+		list, ok := item.ValueExpr.(*hclsyntax.TupleConsExpr)
+		if !ok {
+			continue
+		}
+		for _, e := range list.Exprs {
+			if ref := ProviderRef(e); ref != "" {
+				out = append(out, ref)
+			}
+		}
+	}
+	return out
+}
+
+// ProviderRef renders aws or aws.west from a provider reference expression.
+func ProviderRef(expr hclsyntax.Expression) string {
+	tr, diags := hcl.AbsTraversalForExpr(expr)
+	if diags.HasErrors() || len(tr) == 0 || len(tr) > 2 {
+		return ""
+	}
+	ref := tr.RootName()
+	if len(tr) == 2 {
+		attr, ok := tr[1].(hcl.TraverseAttr)
+		if !ok {
+			return ""
+		}
+		ref += "." + attr.Name
+	}
+	return ref
+}
@@ -0,0 +1,188 @@
+// Package modules finds the directory behind a module call and reads what a module exposes to
+// its callers.
+package modules
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Resolver maps module call sources to directories: relative paths directly, registry and git
+// sources through the .terraform/modules/modules.json that terraform init writes, or else a
+// vendor directory. Nothing is downloaded.
+type Resolver struct {
+	Root string
+	// VendorDir holds vendored modules laid out by address, e.g.
+	// <vendor>/terraform-aws-modules/vpc/aws or <vendor>/github.com/org/repo/subdir.
+	VendorDir string
+
+	manifests map[string]*manifest // dir with .terraform → manifest (nil when absent)
+}
+
+type manifest struct {
+	byKey map[string]string // module key (vpc, vpc.subnets) → dir
+	keyOf map[string]string // dir → module key
+	bySrc map[string][]string
+}
+
+type manifestFile struct {
+	Modules []struct {
+		Key    string `json:"Key"`
+		Source string `json:"Source"`
+		Dir    string `json:"Dir"`
+	} `json:"Modules"`
+}
+
+// NewResolver returns a resolver for the tree at root; vendorDir may be empty.
+func NewResolver(root, vendorDir string) *Resolver {
+	return &Resolver{Root: filepath.Clean(root), VendorDir: vendorDir, manifests: map[string]*manifest{}}
+}
+
+// IsLocal reports whether source is a relative path.
+func IsLocal(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+// Resolve returns the directory of module call name with the given source, declared in parentDir.
+// Local sources must stay inside the root; remote ones are only found when already on disk.
+func (r *Resolver) Resolve(parentDir, name, source string) (string, bool) {
+	if source == "" {
+		return "", false
+	}
+	parentDir = filepath.Clean(parentDir)
+	if IsLocal(source) || filepath.IsAbs(source) {
+		target := source
+		if !filepath.IsAbs(source) {
+			target = filepath.Join(parentDir, source)
+		}
+		target = filepath.Clean(target)
+		if !r.within(target) || !isDir(target) {
+			return "", false
+		}
+		return target, true
+	}
+	if dir, ok := r.fromManifest(parentDir, name, source); ok {
+		return dir, true
+	}
+	if r.VendorDir != "" {
+		if dir := filepath.Join(r.VendorDir, VendorPath(source)); isDir(dir) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// Vendored reports whether path lies in the vendor directory.
+func (r *Resolver) Vendored(path string) bool {
+	if r == nil || r.VendorDir == "" {
+		return false
+	}
+	path = filepath.Clean(path)
+	return path == r.VendorDir || strings.HasPrefix(path, r.VendorDir+string(os.PathSeparator))
+}
+
+// WithoutVendored drops the files of the vendor directory, which are read but never scanned.
+func (r *Resolver) WithoutVendored(files []string) []string {
+	var out []string
+	for _, f := range files {
+		if !r.Vendored(f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (r *Resolver) fromManifest(parentDir, name, source string) (string, bool) {
+	for dir := parentDir; r.within(dir); dir = filepath.Dir(dir) {
+		if m := r.manifest(dir); m != nil {
+			if key, ok := m.keyOf[parentDir]; ok {
+				if key != "" {
+					name = key + "." + name
+				}
+				if child, ok := m.byKey[name]; ok {
+					return child, true
+				}
+			}
+			// llamada fuera del árbol de claves (p. ej. manifest desactualizado): basta una fuente inequívoca
+			if dirs := m.bySrc[source]; len(dirs) == 1 {
+				return dirs[0], true
+			}
+			return "", false
+		}
+		if dir == r.Root || filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	return "", false
+}
+
+func (r *Resolver) within(path string) bool {
+	rel, err := filepath.Rel(r.Root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
+
+func (r *Resolver) manifest(dir string) *manifest {
+	if m, ok := r.manifests[dir]; ok {
+		return m
+	}
+	var m *manifest
+	data, err := os.ReadFile(filepath.Join(dir, ".terraform", "modules", "modules.json"))
+	var mf manifestFile
+	if err == nil && json.Unmarshal(data, &mf) == nil {
+		m = &manifest{byKey: map[string]string{}, keyOf: map[string]string{}, bySrc: map[string][]string{}}
+		for _, e := range mf.Modules {
+			abs := filepath.Clean(filepath.Join(dir, filepath.FromSlash(e.Dir)))
+			if !isDir(abs) {
+				continue
+			}
+			m.byKey[e.Key] = abs
+			m.keyOf[abs] = e.Key
+			if e.Source != "" && !contains(m.bySrc[e.Source], abs) {
+				m.bySrc[e.Source] = append(m.bySrc[e.Source], abs)
+			}
+		}
+	}
+	r.manifests[dir] = m
+	return m
+}
+
+// VendorPath turns a module source into its path below the vendor directory:
+// git::https://github.com/org/repo.git//sub?ref=v1 becomes github.com/org/repo/sub and
+// registry.terraform.io/ns/name/provider becomes ns/name/provider.
+func VendorPath(source string) string {
+	if i := strings.Index(source, "::"); i >= 0 {
+		source = source[i+2:]
+	}
+	if i := strings.Index(source, "://"); i >= 0 {
+		source = source[i+3:]
+	}
+	if strings.HasPrefix(source, "git@") {
+		source = strings.Replace(strings.TrimPrefix(source, "git@"), ":", "/", 1)
+	}
+	if i := strings.IndexByte(source, '?'); i >= 0 {
+		source = source[:i]
+	}
+	sub := ""
+	if i := strings.Index(source, "//"); i >= 0 {
+		source, sub = source[:i], source[i+2:]
+	}
+	source = strings.TrimSuffix(source, ".git")
+	source = strings.TrimPrefix(source, "registry.terraform.io/")
+	return filepath.FromSlash(strings.Trim(source+"/"+sub, "/"))
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package modules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	stack := filepath.Join(root, "stacks", "prod")
+	writeFile(t, filepath.Join(stack, "main.tf"), `module "vpc" { source = "terraform-aws-modules/vpc/aws" }`)
+	writeFile(t, filepath.Join(stack, ".terraform/modules/vpc/main.tf"), `module "subnets" { source = "./modules/subnets" }`)
+	writeFile(t, filepath.Join(stack, ".terraform/modules/vpc/modules/subnets/main.tf"), ``)
+	writeFile(t, filepath.Join(stack, ".terraform/modules/modules.json"), `{"Modules":[
+  {"Key":"","Source":"","Dir":"."},
+  {"Key":"vpc","Source":"registry.terraform.io/terraform-aws-modules/vpc/aws","Version":"5.0.0","Dir":".terraform/modules/vpc"},
+  {"Key":"vpc.subnets","Source":"./modules/subnets","Dir":".terraform/modules/vpc/modules/subnets"}
+]}`)
+	writeFile(t, filepath.Join(root, "vendor/github.com/org/network/dns/main.tf"), ``)
+	writeFile(t, filepath.Join(root, "local/main.tf"), ``)
+
+	r := NewResolver(root, filepath.Join(root, "vendor"))
+	vpc := filepath.Join(stack, ".terraform", "modules", "vpc")
+	cases := []struct {
+		parent, name, source, want string
+	}{
+		{root, "local", "./local", filepath.Join(root, "local")},
+		{root, "escape", "../outside", ""},
+		{stack, "vpc", "terraform-aws-modules/vpc/aws", vpc},
+		{vpc, "subnets", "./modules/subnets", filepath.Join(vpc, "modules", "subnets")},
+		// no key for this call, but the source is unambiguous in the manifest
+		{stack, "vpc_copy", "registry.terraform.io/terraform-aws-modules/vpc/aws", vpc},
+		{root, "dns", "git::https://github.com/org/network.git//dns?ref=v1.2.0", filepath.Join(root, "vendor", "github.com", "org", "network", "dns")},
+		{root, "missing", "hashicorp/consul/aws", ""},
+	}
+	for _, tc := range cases {
+		got, ok := r.Resolve(tc.parent, tc.name, tc.source)
+		if got != tc.want || ok != (tc.want != "") {
+			t.Errorf("Resolve(%s, %q) = %q, %v; want %q", tc.name, tc.source, got, ok, tc.want)
+		}
+	}
+	if !r.Vendored(filepath.Join(root, "vendor", "x.tf")) || r.Vendored(filepath.Join(root, "vendors.tf")) {
+		t.Errorf("Vendored misclassifies paths")
+	}
+}
+
+func TestVendorPath(t *testing.T) {
+	cases := map[string]string{
+		"terraform-aws-modules/vpc/aws":                        "terraform-aws-modules/vpc/aws",
+		"registry.terraform.io/hashicorp/consul/aws":           "hashicorp/consul/aws",
+		"git::https://github.com/org/repo.git//sub/dir?ref=v1": "github.com/org/repo/sub/dir",
+		"git@github.com:org/repo.git":                          "github.com/org/repo",
+		"github.com/org/repo":                                  "github.com/org/repo",
+	}
+	for source, want := range cases {
+		if got := VendorPath(source); got != filepath.FromSlash(want) {
+			t.Errorf("VendorPath(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.tf"), `terraform {
+  required_providers {
+    aws = {
+      source                = "hashicorp/aws"
+      configuration_aliases = [aws.east, aws.west]
+    }
+  }
+}
+
+variable "name" {}
+
+variable "cidr" {
+  default = "10.0.0.0/16"
+}
+
+output "id" {
+  value = "x"
+}
+`)
+	mi, err := Read(dir)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !mi.Variables["name"] || mi.Variables["cidr"] || !mi.Outputs["id"] || !mi.Providers["aws"] {
+		t.Fatalf("unexpected interface: %+v", mi)
+	}
+	if strings.Join(mi.Aliases, ",") != "aws.east,aws.west" {
+		t.Fatalf("unexpected aliases: %v", mi.Aliases)
+	}
+}
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/model"
+)
+
+// moduleVars resuelve los defaults literales de las variables del módulo, cargados solo si
+// alguna regla de atributo los necesita.
+type moduleVars struct {
+	path     string
+	body     *hclsyntax.Body
+	defaults map[string]string
+}
+
+func (m *moduleVars) lookup(name string) (string, bool) {
+	if m.defaults == nil {
+		m.defaults = map[string]string{}
+		collectDefaults(m.body, m.defaults)
+		entries, _ := os.ReadDir(filepath.Dir(m.path))
+		for _, e := range entries {
+			sibling := filepath.Join(filepath.Dir(m.path), e.Name())
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".tf") || sameFile(sibling, m.path) {
+				continue
+			}
+			src, err := os.ReadFile(sibling)
+			if err != nil {
+				continue
+			}
+			file, diags := hclsyntax.ParseConfig(src, sibling, hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				continue
+			}
+			if body, ok := file.Body.(*hclsyntax.Body); ok {
+				collectDefaults(body, m.defaults)
+			}
+		}
+	}
+	v, ok := m.defaults[name]
+	return v, ok
+}
+
+func sameFile(a, b string) bool {
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+func collectDefaults(body *hclsyntax.Body, out map[string]string) {
+	for _, b := range body.Blocks {
+		if b.Type != "variable" || len(b.Labels) == 0 {
+			continue
+		}
+		attr, ok := b.Body.Attributes["default"]
+		if !ok {
+			continue
+		}
+		if v, ok := literalString(attr.Expr, nil); ok {
+			out[b.Labels[0]] = v
+		}
+	}
+}
+
+// evalAttributes checks the attribute values of a resource against the attribute rules of its type.
+func evalAttributes(findings *[]model.Finding, path string, block *hclsyntax.Block, cfg *config.Config, vars *moduleVars) {
+	typ, name := block.Labels[0], block.Labels[1]
+	for _, rule := range cfg.AttributeRules(typ) {
+		expr := attributeExpr(block.Body, rule.Path())
+		if expr == nil {
+			continue
+		}
+		value, ok := literalString(expr, vars)
+		if !ok || rule.IsIgnored(value) {
+			continue
+		}
+		subject := rule.Subject(typ, name)
+		var msgs []string
+		if !rule.Matches(value) {
+			msgs = append(msgs, rule.Explain(subject, value))
+		}
+		msgs = append(msgs, rule.Violations(subject, "", value)...)
+		rng := expr.Range()
+		for _, msg := range msgs {
+			*findings = append(*findings, model.Finding{
+				File:      path,
+				Line:      rng.Start.Line,
+				Column:    rng.Start.Column,
+				EndLine:   rng.End.Line,
+				EndColumn: rng.End.Column,
+				Kind:      "attribute",
+				Name:      typ + "." + name + "." + rule.Attribute,
+				Message:   msg,
+			})
+		}
+	}
+}
+
+// attributeExpr finds path in body: an attribute, then a key of the object it is set to.
+func attributeExpr(body *hclsyntax.Body, path []string) hclsyntax.Expression {
+	attr, ok := body.Attributes[path[0]]
+	if !ok {
+		return nil
+	}
+	expr := attr.Expr
+	for _, key := range path[1:] {
+		obj, ok := expr.(*hclsyntax.ObjectConsExpr)
+		if !ok {
+			return nil
+		}
+		expr = nil
+		for _, item := range obj.Items {
+			if k, ok := objectKey(item.KeyExpr); ok && k == key {
+				expr = item.ValueExpr
+			}
+		}
+		if expr == nil {
+			return nil
+		}
+	}
+	return expr
+}
+
+func objectKey(expr hclsyntax.Expression) (string, bool) {
+	if k, ok := expr.(*hclsyntax.ObjectConsKeyExpr); ok {
+		if name := hcl.ExprAsKeyword(k.Wrapped); name != "" && !k.ForceNonLiteral {
+			return name, true
+		}
+		expr = k.Wrapped
+	}
+	return literalString(expr, nil)
+}
+
+// literalString evaluates expr when it only combines literals and, with vars, variables that
+// have a literal default. Anything else (locals, functions, resource attributes) is unknown.
+func literalString(expr hclsyntax.Expression, vars *moduleVars) (string, bool) {
+	switch e := expr.(type) {
+	case *hclsyntax.LiteralValueExpr:
+		return ctyString(e.Val)
+	case *hclsyntax.TemplateWrapExpr:
+		return literalString(e.Wrapped, vars)
+	case *hclsyntax.TemplateExpr:
+		var sb strings.Builder
+		for _, part := range e.Parts {
+			s, ok := literalString(part, vars)
+			if !ok {
+				return "", false
+			}
+			sb.WriteString(s)
+		}
+		return sb.String(), true
+	case *hclsyntax.ScopeTraversalExpr:
+		if vars == nil || len(e.Traversal) != 2 || e.Traversal.RootName() != "var" {
+			return "", false
+		}
+		if attr, ok := e.Traversal[1].(hcl.TraverseAttr); ok {
+			return vars.lookup(attr.Name)
+		}
+	}
+	return "", false
+}
+
+func ctyString(v cty.Value) (string, bool) {
+	if v.IsNull() || !v.IsKnown() {
+		return "", false
+	}
+	s, err := convert.Convert(v, cty.String)
+	if err != nil {
+		return "", false
+	}
+	return s.AsString(), true
+}
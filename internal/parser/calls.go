@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/model"
+	"github.com/josdagaro/tfsuit/internal/modules"
+)
+
+// moduleMetaArgs are the module block arguments that don't set child variables.
+var moduleMetaArgs = map[string]bool{
+	"source": true, "version": true, "providers": true, "count": true, "for_each": true, "depends_on": true,
+}
+
+// CheckModuleCalls compares the arguments of every resolvable module call with the variables of
+// the child: arguments no variable accepts, and required variables (no default) left unset.
+func CheckModuleCalls(files []string, res *modules.Resolver) []model.Finding {
+	children := map[string]*modules.Interface{}
+	var findings []model.Finding
+
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			continue
+		}
+		dir := filepath.Dir(path)
+		for _, b := range file.Body.(*hclsyntax.Body).Blocks {
+			if b.Type != "module" || len(b.Labels) == 0 {
+				continue
+			}
+			attr, ok := b.Body.Attributes["source"]
+			if !ok {
+				continue
+			}
+			source, ok := literalString(attr.Expr, nil)
+			if !ok {
+				continue
+			}
+			childDir, ok := res.Resolve(dir, b.Labels[0], source)
+			if !ok {
+				continue
+			}
+			child, ok := children[childDir]
+			if !ok {
+				child, _ = modules.Read(childDir)
+				children[childDir] = child
+			}
+			if child == nil || child.Files == 0 {
+				continue
+			}
+			findings = append(findings, checkCall(path, b, source, child)...)
+		}
+	}
+	return findings
+}
+
+func checkCall(path string, b *hclsyntax.Block, source string, child *modules.Interface) []model.Finding {
+	name := b.Labels[0]
+	var findings []model.Finding
+	add := func(rng hcl.Range, msg string) {
+		findings = append(findings, model.Finding{
+			File:    path,
+			Line:    rng.Start.Line,
+			Column:  rng.Start.Column,
+			Kind:    "module",
+			Name:    name,
+			Message: msg,
+		})
+	}
+
+	args := make([]*hclsyntax.Attribute, 0, len(b.Body.Attributes))
+	for arg, attr := range b.Body.Attributes {
+		if !moduleMetaArgs[arg] {
+			args = append(args, attr)
+		}
+	}
+	sort.Slice(args, func(i, j int) bool { return args[i].SrcRange.Start.Byte < args[j].SrcRange.Start.Byte })
+	for _, attr := range args {
+		if _, ok := child.Variables[attr.Name]; !ok {
+			add(attr.NameRange, fmt.Sprintf("module '%s' passes '%s', but %s declares no such variable", name, attr.Name, source))
+		}
+	}
+
+	var missing []string
+	for v, required := range child.Variables {
+		if _, set := b.Body.Attributes[v]; required && !set {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		add(b.DefRange(), fmt.Sprintf("module '%s' does not set required variables of %s: %s", name, source, strings.Join(missing, ", ")))
+	}
+	return findings
+}
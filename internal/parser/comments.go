@@ -0,0 +1,43 @@
+package parser
+
+import (
+	hcl "github.com/hashicorp/hcl/v2"
+	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// CommentLines returns the 1-based lines of src that hold nothing but comments, `#`, `//` or
+// `/* */`, including the inner lines of a multi-line one. It works from the hclsyntax tokens, so
+// a `#` inside a string or heredoc is not taken for a comment.
+func CommentLines(src []byte) map[int]bool {
+	tokens, _ := hclsyntax.LexConfig(src, "", hcl.Pos{Line: 1, Column: 1})
+	comments, code := map[int]bool{}, map[int]bool{}
+	for _, tok := range tokens {
+		if tok.Type == hclsyntax.TokenNewline || tok.Type == hclsyntax.TokenEOF {
+			continue
+		}
+		end := tok.Range.End.Line
+		if end > tok.Range.Start.Line && tok.Range.End.Column == 1 {
+			end-- // # y // incluyen el salto de línea
+		}
+		lines := code
+		if tok.Type == hclsyntax.TokenComment {
+			lines = comments
+		}
+		for n := tok.Range.Start.Line; n <= end; n++ {
+			lines[n] = true
+		}
+	}
+	for n := range code {
+		delete(comments, n)
+	}
+	return comments
+}
+
+// CommentStart moves line up over the comment lines (see CommentLines) right above it, staying
+// below floor.
+func CommentStart(comments map[int]bool, line, floor int) int {
+	for line-1 > floor && comments[line-1] {
+		line--
+	}
+	return line
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"fmt"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/model"
+)
+
+// InstanceKey is a key of a literal for_each: a map key or an element of toset([...]).
+type InstanceKey struct {
+	Key   string
+	Range hcl.Range
+}
+
+// InstanceKeys returns the literal keys of block's for_each. Computed maps and sets, and the
+// computed entries of literal ones, are skipped.
+func InstanceKeys(block *hclsyntax.Block) []InstanceKey {
+	attr, ok := block.Body.Attributes["for_each"]
+	if !ok {
+		return nil
+	}
+	var keys []InstanceKey
+	switch e := attr.Expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		for _, item := range e.Items {
+			if k, ok := objectKey(item.KeyExpr); ok {
+				keys = append(keys, InstanceKey{Key: k, Range: item.KeyExpr.Range()})
+			}
+		}
+	case *hclsyntax.FunctionCallExpr:
+		if e.Name != "toset" || len(e.Args) != 1 {
+			return nil
+		}
+		tuple, ok := e.Args[0].(*hclsyntax.TupleConsExpr)
+		if !ok {
+			return nil
+		}
+		for _, el := range tuple.Exprs {
+			if k, ok := literalString(el, nil); ok {
+				keys = append(keys, InstanceKey{Key: k, Range: el.Range()})
+			}
+		}
+	}
+	return keys
+}
+
+// checkInstanceKeys applies the instance_keys rule to the literal for_each keys of a resource or
+// module block and, with prefer_for_each, reports count = length(...).
+func checkInstanceKeys(path string, src []byte, block *hclsyntax.Block, address string, rule *config.Rule) []model.Finding {
+	if rule == nil {
+		return nil
+	}
+	var findings []model.Finding
+	for _, k := range InstanceKeys(block) {
+		if rule.IsIgnored(k.Key) {
+			continue
+		}
+		var msgs []string
+		if !rule.Matches(k.Key) {
+			msgs = append(msgs, rule.Explain("instance key", k.Key))
+		}
+		msgs = append(msgs, rule.Violations("instance key", "", k.Key)...)
+		for _, msg := range msgs {
+			findings = append(findings, model.Finding{
+				File:      path,
+				Line:      k.Range.Start.Line,
+				Column:    k.Range.Start.Column,
+				EndLine:   k.Range.End.Line,
+				EndColumn: k.Range.End.Column,
+				Kind:      "instance_key",
+				Name:      fmt.Sprintf("%s[%q]", address, k.Key),
+				Message:   msg,
+			})
+		}
+	}
+
+	if !rule.ForEachPreferred() {
+		return findings
+	}
+	attr, ok := block.Body.Attributes["count"]
+	if !ok {
+		return findings
+	}
+	// count = length(lista): las claves son posiciones y se corren al quitar un elemento
+	if call, ok := attr.Expr.(*hclsyntax.FunctionCallExpr); ok && call.Name == "length" && len(call.Args) == 1 {
+		list := string(call.Args[0].Range().SliceBytes(src))
+		findings = append(findings, model.Finding{
+			File:    path,
+			Line:    attr.SrcRange.Start.Line,
+			Column:  attr.SrcRange.Start.Column,
+			Kind:    "instance_key",
+			Name:    address,
+			Message: fmt.Sprintf("%s sets count = length(%s); for_each keeps instance keys stable when the list changes", address, list),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/model"
+)
+
+// checkLayout reports the top-level blocks declared in a file the layout does not allow for their kind.
+func checkLayout(path string, body *hclsyntax.Body, layout *config.Layout) []model.Finding {
+	if layout == nil {
+		return nil
+	}
+	name := filepath.Base(path)
+	var findings []model.Finding
+	for _, b := range body.Blocks {
+		if layout.Allows(b.Type, name) {
+			continue
+		}
+		label := layoutName(b)
+		rng := nameRange(b)
+		findings = append(findings, model.Finding{
+			File:    path,
+			Line:    rng.Start.Line,
+			Column:  rng.Start.Column,
+			Kind:    "layout",
+			Name:    label,
+			Message: fmt.Sprintf("%s is declared in %s, expected %s", label, name, strings.Join(layout.Files(b.Type), " or ")),
+		})
+	}
+	return findings
+}
+
+// layoutName describes a block in layout findings: provider "aws.east", variable "region", locals.
+func layoutName(b *hclsyntax.Block) string {
+	switch {
+	case b.Type == "provider" && len(b.Labels) > 0:
+		ref := b.Labels[0]
+		if attr, ok := b.Body.Attributes["alias"]; ok {
+			if alias, ok := literalString(attr.Expr, nil); ok {
+				ref += "." + alias
+			}
+		}
+		return fmt.Sprintf("provider '%s'", ref)
+	case len(b.Labels) > 0:
+		return fmt.Sprintf("%s '%s'", b.Type, strings.Join(b.Labels, "."))
+	}
+	return b.Type
+}
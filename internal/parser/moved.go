@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/josdagaro/tfsuit/internal/model"
+	"github.com/josdagaro/tfsuit/internal/modules"
+	"github.com/josdagaro/tfsuit/internal/refs"
+)
+
+// CheckTargets reports moved and import blocks whose `to` address is not declared, following
+// module.<call> steps into the child modules res resolves.
+func CheckTargets(files []string, res *modules.Resolver) ([]model.Finding, error) {
+	ix, err := refs.Build(files)
+	if err != nil {
+		return nil, err
+	}
+	ix.ResolveChild = res.Resolve
+	dirs := make([]string, 0, len(ix.Modules))
+	for dir := range ix.Modules {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var findings []model.Finding
+	for _, dir := range dirs {
+		for _, t := range ix.Modules[dir].Targets {
+			if ix.Exists(dir, t.Address) {
+				continue
+			}
+			findings = append(findings, model.Finding{
+				File:    t.File,
+				Line:    t.Line,
+				Column:  t.Range.Start.Column,
+				Kind:    t.Block,
+				Name:    t.Address,
+				Message: fmt.Sprintf("%s target '%s' is not declared", t.Block, t.Address),
+			})
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/model"
+)
+
+// BodyItem is an attribute or nested block of a body; Name is the block type for blocks.
+type BodyItem struct {
+	Name  string
+	Block bool
+	Range hcl.Range
+}
+
+// BodyItems returns the attributes and nested blocks of body in source order.
+func BodyItems(body *hclsyntax.Body) []BodyItem {
+	var items []BodyItem
+	for name, attr := range body.Attributes {
+		items = append(items, BodyItem{Name: name, Range: attr.SrcRange})
+	}
+	for _, b := range body.Blocks {
+		items = append(items, BodyItem{Name: b.Type, Block: true, Range: b.Range()})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Range.Start.Byte < items[j].Range.Start.Byte })
+	return items
+}
+
+func (it BodyItem) describe() string {
+	if it.Block {
+		return it.Name + " block"
+	}
+	return "'" + it.Name + "'"
+}
+
+// Arranged reports whether the items of block already follow ordering.meta_arguments.
+func Arranged(block *hclsyntax.Block, ordering *config.Ordering) bool {
+	items := BodyItems(block.Body)
+	names := make([]string, len(items))
+	for i, it := range items {
+		names[i] = it.Name
+	}
+	for i, idx := range ordering.Arrange(block.Type, names) {
+		if i != idx {
+			return false
+		}
+	}
+	return true
+}
+
+// checkOrdering reports blocks out of alphabetical order for the kinds in ordering.sort, and
+// resource, data and module blocks whose meta-arguments are not where ordering.meta_arguments says.
+func checkOrdering(path string, body *hclsyntax.Body, infos []blockInfo, ordering *config.Ordering) []model.Finding {
+	if ordering == nil {
+		return nil
+	}
+	var findings []model.Finding
+	last := map[string]blockInfo{}
+	for _, info := range infos {
+		if !ordering.Sorts(info.Kind) {
+			continue
+		}
+		if prev, ok := last[info.Kind]; ok && info.sortKey() < prev.sortKey() {
+			findings = append(findings, model.Finding{
+				File:    path,
+				Line:    info.StartLine,
+				Column:  info.Column,
+				Kind:    "ordering",
+				Name:    info.sortKey(),
+				Message: fmt.Sprintf("%s '%s' is out of alphabetical order (after '%s')", info.Kind, info.sortKey(), prev.sortKey()),
+			})
+		}
+		last[info.Kind] = info
+	}
+
+	if ordering.MetaArguments == "" {
+		return findings
+	}
+	for _, b := range body.Blocks {
+		switch b.Type {
+		case "resource", "data", "module":
+		default:
+			continue
+		}
+		if len(b.Labels) == 0 || Arranged(b, ordering) {
+			continue
+		}
+		name := strings.Join(b.Labels, ".")
+		rng := nameRange(b)
+		findings = append(findings, model.Finding{
+			File:    path,
+			Line:    rng.Start.Line,
+			Column:  rng.Start.Column,
+			Kind:    "ordering",
+			Name:    name,
+			Message: fmt.Sprintf("%s '%s' should list its meta-arguments %s (%s)", b.Type, name, ordering.MetaArguments, strings.Join(config.MetaArguments, ", ")),
+		})
+	}
+	return findings
+}
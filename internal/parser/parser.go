@@ -3,32 +3,54 @@ package parser
 import (
 	"fmt"
 	"os"
-	"strings"
 
 	hcl "github.com/hashicorp/hcl/v2"
 	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
 
 	"github.com/josdagaro/tfsuit/internal/config"
 	"github.com/josdagaro/tfsuit/internal/model"
 )
 
 type blockInfo struct {
-	Kind       string
-	Name       string
-	StartLine  int
-	EndLine    int
-	SingleLine bool
+	Kind      string
+	Type      string // resource/data type
+	Name      string
+	StartLine int
+	EndLine   int
+	Column    int // of the name label
 }
 
 func newBlockInfo(kind, name string, block *hclsyntax.Block) blockInfo {
 	rng := block.Range()
+	typ := ""
+	if (kind == "resource" || kind == "data") && len(block.Labels) > 1 {
+		typ = block.Labels[0]
+	}
 	return blockInfo{
-		Kind:       kind,
-		Name:       name,
-		StartLine:  rng.Start.Line,
-		EndLine:    rng.End.Line,
-		SingleLine: rng.Start.Line == rng.End.Line,
+		Kind:      kind,
+		Type:      typ,
+		Name:      name,
+		StartLine: rng.Start.Line,
+		EndLine:   rng.End.Line,
+		Column:    nameRange(block).Start.Column,
+	}
+}
+
+// nameRange is the range of the block's name, its last label; DefRange for unlabeled blocks.
+func nameRange(block *hclsyntax.Block) hcl.Range {
+	if n := len(block.LabelRanges); n > 0 {
+		return block.LabelRanges[n-1]
+	}
+	return block.DefRange()
+}
+
+// sortKey orders blocks of one kind: the label, or TYPE.NAME for resources and data sources.
+func (b blockInfo) sortKey() string {
+	if b.Type != "" {
+		return b.Type + "." + b.Name
 	}
+	return b.Name
 }
 
 // ParseFile extrae identificadores y devuelve violaciones según las reglas.
@@ -50,6 +72,7 @@ func ParseFile(path string, cfg *config.Config) ([]model.Finding, error) {
 
 	var findings []model.Finding
 	var blockInfos []blockInfo
+	vars := &moduleVars{path: path, body: syntaxBody}
 
 	for _, block := range syntaxBody.Blocks {
 		switch block.Type {
@@ -58,7 +81,7 @@ func ParseFile(path string, cfg *config.Config) ([]model.Finding, error) {
 				continue
 			}
 			name := block.Labels[0]
-			evalRule(&findings, path, block, "variable", name, &cfg.Variables)
+			evalRule(&findings, path, block, "variable", name, cfg.Variables)
 			blockInfos = append(blockInfos, newBlockInfo("variable", name, block))
 
 		case "output":
@@ -66,7 +89,7 @@ func ParseFile(path string, cfg *config.Config) ([]model.Finding, error) {
 				continue
 			}
 			name := block.Labels[0]
-			evalRule(&findings, path, block, "output", name, &cfg.Outputs)
+			evalRule(&findings, path, block, "output", name, cfg.Outputs)
 			blockInfos = append(blockInfos, newBlockInfo("output", name, block))
 
 		case "module":
@@ -74,7 +97,8 @@ func ParseFile(path string, cfg *config.Config) ([]model.Finding, error) {
 				continue
 			}
 			name := block.Labels[0]
-			evalRule(&findings, path, block, "module", name, &cfg.Modules)
+			evalRule(&findings, path, block, "module", name, cfg.Modules)
+			findings = append(findings, checkInstanceKeys(path, src, block, "module."+name, cfg.InstanceKeys)...)
 			blockInfos = append(blockInfos, newBlockInfo("module", name, block))
 
 		case "resource":
@@ -83,7 +107,9 @@ func ParseFile(path string, cfg *config.Config) ([]model.Finding, error) {
 				continue
 			}
 			name := block.Labels[1]
-			evalRule(&findings, path, block, "resource", name, &cfg.Resources)
+			evalRule(&findings, path, block, "resource", name, cfg.Resources)
+			evalAttributes(&findings, path, block, cfg, vars)
+			findings = append(findings, checkInstanceKeys(path, src, block, block.Labels[0]+"."+name, cfg.InstanceKeys)...)
 			blockInfos = append(blockInfos, newBlockInfo("resource", name, block))
 
 		case "data":
@@ -93,11 +119,20 @@ func ParseFile(path string, cfg *config.Config) ([]model.Finding, error) {
 			name := block.Labels[1]
 			evalRule(&findings, path, block, "data", name, cfg.Data)
 			blockInfos = append(blockInfos, newBlockInfo("data", name, block))
+
+		case "check":
+			if len(block.Labels) == 0 {
+				continue
+			}
+			evalRule(&findings, path, block, "check", block.Labels[0], cfg.Checks)
 		}
 	}
 
+	findings = append(findings, checkLayout(path, syntaxBody, cfg.Layout)...)
+	findings = append(findings, checkOrdering(path, syntaxBody, blockInfos, cfg.Ordering)...)
+
 	if cfg.Spacing != nil && cfg.Spacing.EnabledValue() {
-		spacingFindings := checkBlockSpacing(path, src, blockInfos, cfg.Spacing)
+		spacingFindings := checkBlockSpacing(path, src, syntaxBody, cfg.Spacing)
 		findings = append(findings, spacingFindings...)
 	}
 
@@ -113,11 +148,13 @@ func evalRule(findings *[]model.Finding, path string, block *hclsyntax.Block, ki
 		return
 	}
 
+	rng := nameRange(block)
 	if rule.RequiresProvider() {
 		if !hasRequiredProvider(block, kind) {
 			*findings = append(*findings, model.Finding{
 				File:    path,
-				Line:    block.DefRange().Start.Line,
+				Line:    rng.Start.Line,
+				Column:  rng.Start.Column,
 				Kind:    kind,
 				Name:    name,
 				Message: providerMessage(kind, name),
@@ -125,18 +162,73 @@ func evalRule(findings *[]model.Finding, path string, block *hclsyntax.Block, ki
 		}
 	}
 
+	if kind == "variable" || kind == "output" {
+		evalHygiene(findings, path, block, kind, name, rule)
+	}
+
+	typ := ""
+	if kind == "resource" || kind == "data" {
+		typ = block.Labels[0]
+	}
+	for _, msg := range rule.Violations(kind, typ, name) {
+		*findings = append(*findings, model.Finding{
+			File:    path,
+			Line:    rng.Start.Line,
+			Column:  rng.Start.Column,
+			Kind:    kind,
+			Name:    name,
+			Message: msg,
+		})
+	}
+
 	if rule.Matches(name) {
 		return
 	}
 	*findings = append(*findings, model.Finding{
 		File:    path,
-		Line:    block.DefRange().Start.Line,
+		Line:    rng.Start.Line,
+		Column:  rng.Start.Column,
 		Kind:    kind,
 		Name:    name,
-		Message: fmt.Sprintf("%s '%s' does not match pattern %s", kind, name, rule.Pattern),
+		Message: rule.Explain(kind, name),
 	})
 }
 
+// evalHygiene revisa description, type y sensitive de variables y outputs.
+func evalHygiene(findings *[]model.Finding, path string, block *hclsyntax.Block, kind, name string, rule *config.Rule) {
+	add := func(rng hcl.Range, msg string) {
+		*findings = append(*findings, model.Finding{File: path, Line: rng.Start.Line, Column: rng.Start.Column, Kind: kind, Name: name, Message: msg})
+	}
+	label := nameRange(block)
+	attrs := block.Body.Attributes
+
+	if desc, ok := attrs["description"]; ok {
+		if v, ok := literalString(desc.Expr, nil); ok && !rule.DescriptionOK(v) {
+			add(desc.SrcRange, fmt.Sprintf("%s '%s' description does not match pattern %s", kind, name, rule.DescriptionPattern))
+		}
+	} else if rule.DescriptionRequired() {
+		add(label, fmt.Sprintf("%s '%s' has no description", kind, name))
+	}
+	if _, ok := attrs["type"]; !ok && kind == "variable" && rule.TypeRequired() {
+		add(label, fmt.Sprintf("%s '%s' has no type", kind, name))
+	}
+	if rule.SensitiveRequired(name) && !IsSensitive(block) {
+		add(label, fmt.Sprintf("%s '%s' must be marked sensitive = true", kind, name))
+	}
+}
+
+// IsSensitive reports whether block sets sensitive, unless it is literally false.
+func IsSensitive(block *hclsyntax.Block) bool {
+	attr, ok := block.Body.Attributes["sensitive"]
+	if !ok {
+		return false
+	}
+	if lit, ok := attr.Expr.(*hclsyntax.LiteralValueExpr); ok && lit.Val.Type() == cty.Bool && !lit.Val.IsNull() {
+		return lit.Val.True()
+	}
+	return true
+}
+
 func hasRequiredProvider(block *hclsyntax.Block, kind string) bool {
 	switch kind {
 	case "module":
@@ -163,41 +255,6 @@ func providerMessage(kind, name string) string {
 	return fmt.Sprintf("%s '%s' must set a provider", kind, name)
 }
 
-func checkBlockSpacing(path string, src []byte, infos []blockInfo, spacing *config.BlockSpacing) []model.Finding {
-	if spacing == nil || !spacing.EnabledValue() || len(infos) < 2 {
-		return nil
-	}
-	lines := splitLines(src)
-	var findings []model.Finding
-
-	for i := 0; i < len(infos)-1; i++ {
-		current := infos[i]
-		next := infos[i+1]
-
-		if spacing.AllowCompactKind(current.Kind) && spacing.AllowCompactKind(next.Kind) &&
-			current.SingleLine && next.SingleLine {
-			continue
-		}
-
-		actual := countBlankLinesBetween(lines, current.EndLine, next.StartLine)
-		if actual >= spacing.MinLines() {
-			continue
-		}
-		f := model.Finding{
-			File: path,
-			Line: next.StartLine,
-			Kind: "spacing",
-			Name: fmt.Sprintf("%s/%s", current.Kind, next.Kind),
-			Message: fmt.Sprintf(
-				"expected at least %d blank line(s) between %s '%s' and %s '%s'",
-				spacing.MinLines(), current.Kind, current.Name, next.Kind, next.Name,
-			),
-		}
-		findings = append(findings, f)
-	}
-	return findings
-}
-
 func splitLines(src []byte) []string {
 	var lines []string
 	start := 0
@@ -212,20 +269,3 @@ func splitLines(src []byte) []string {
 	}
 	return lines
 }
-
-func countBlankLinesBetween(lines []string, endLine, nextStart int) int {
-	if nextStart <= endLine+1 {
-		return 0
-	}
-	blank := 0
-	for lineNum := endLine + 1; lineNum <= nextStart-1; lineNum++ {
-		idx := lineNum - 1
-		if idx < 0 || idx >= len(lines) {
-			continue
-		}
-		if strings.TrimSpace(lines[idx]) == "" {
-			blank++
-		}
-	}
-	return blank
-}
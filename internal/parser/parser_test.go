@@ -1,11 +1,14 @@
 package parser_test
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/modules"
 	"github.com/josdagaro/tfsuit/internal/parser"
 )
 
@@ -24,6 +27,14 @@ func TestParserDetectsViolations(t *testing.T) {
 	if len(findings) != 3 {
 		t.Fatalf("want 3 findings, got %d", len(findings))
 	}
+	// las columnas apuntan al label del nombre
+	var cols []string
+	for _, f := range findings {
+		cols = append(cols, fmt.Sprintf("%d:%d", f.Line, f.Column))
+	}
+	if got := strings.Join(cols, " "); got != "1:10 3:8 10:26" {
+		t.Fatalf("want name label columns 1:10 3:8 10:26, got %s", got)
+	}
 
 	goodFile := filepath.Join(root, "good.tf")
 	findings, err = parser.ParseFile(goodFile, cfg)
@@ -84,6 +95,98 @@ resources { pattern = ".*" }
 	}
 }
 
+func TestCommentLines(t *testing.T) {
+	src := []byte(`# hash
+// slashes
+/*
+  block
+*/
+variable "a" {} # trailing
+/* inline */ variable "b" {}
+locals {
+  doc = <<EOT
+# not a comment
+EOT
+}
+`)
+	got := parser.CommentLines(src)
+	for line, want := range map[int]bool{1: true, 2: true, 3: true, 4: true, 5: true, 6: false, 7: false, 10: false} {
+		if got[line] != want {
+			t.Fatalf("line %d: comment=%v, want %v", line, got[line], want)
+		}
+	}
+	if start := parser.CommentStart(got, 6, 0); start != 1 {
+		t.Fatalf("comments above line 6 should start at 1, got %d", start)
+	}
+	if start := parser.CommentStart(got, 6, 3); start != 4 {
+		t.Fatalf("floor should stop the walk, got %d", start)
+	}
+}
+
+func TestSpacingCoversAllBlocks(t *testing.T) {
+	dir := t.TempDir()
+	tfPath := filepath.Join(dir, "main.tf")
+	content := `locals {
+  region = "us-east-1"
+}
+provider "aws" {
+  region = local.region
+}
+
+
+
+resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+`
+	if err := os.WriteFile(tfPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write tf: %v", err)
+	}
+	cfgPath := filepath.Join(dir, "tfsuit.hcl")
+	cfgContent := `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources {
+  pattern          = ".*"
+  require_provider = false
+}
+
+block_spacing {
+  max_blank_lines = 2
+  nested { blocks = ["lifecycle"] }
+}
+`
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	findings, err := parser.ParseFile(tfPath, cfg)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var got []string
+	for _, f := range findings {
+		if f.Kind == "spacing" {
+			got = append(got, fmt.Sprintf("%d %s", f.Line, f.Message))
+		}
+	}
+	want := []string{
+		"4 expected at least 1 blank line(s) between locals and provider 'aws'",
+		"10 expected at most 2 blank line(s) between provider 'aws' and resource 'logs'",
+		"12 expected at least 1 blank line(s) between 'bucket' and lifecycle block in resource 'logs'",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("spacing findings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
 func TestRequireProviderEnforcement(t *testing.T) {
 	dir := t.TempDir()
 
@@ -176,3 +279,812 @@ data "aws_region" "with_provider" {
 		}
 	}
 }
+
+func TestTemplateFindingNamesSegment(t *testing.T) {
+	dir := t.TempDir()
+	tfPath := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(tfPath, []byte(`variable "qa_billing" {}`+"\n"), 0o644); err != nil {
+		t.Fatalf("write tf: %v", err)
+	}
+	cfgPath := filepath.Join(dir, "tfsuit.hcl")
+	cfgContent := `
+variables {
+  template = "{env}_{service}"
+
+  segment "env" {
+    values = ["dev", "prd"]
+  }
+}
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	findings, err := parser.ParseFile(tfPath, cfg)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := "variable 'qa_billing' does not match template {env}_{service}: segment {env} is 'qa', expected one of dev, prd"
+	if len(findings) != 1 || findings[0].Message != want {
+		t.Fatalf("unexpected findings: %+v", findings)
+	}
+}
+
+func TestLimitFindingsAreSeparate(t *testing.T) {
+	dir := t.TempDir()
+	tfPath := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(tfPath, []byte(`resource "aws_s3_bucket" "temp_s3_bucket" {}`+"\n"), 0o644); err != nil {
+		t.Fatalf("write tf: %v", err)
+	}
+	cfgPath := filepath.Join(dir, "tfsuit.hcl")
+	cfgContent := `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources {
+  pattern               = "^[a-z_]+$"
+  max_length            = 10
+  forbidden_words       = ["temp"]
+  disallow_type_stutter = true
+}
+`
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	findings, err := parser.ParseFile(tfPath, cfg)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	// pattern (no digits allowed), length, forbidden word and stutter
+	if len(findings) != 4 {
+		t.Fatalf("want 4 findings, got %+v", findings)
+	}
+}
+
+func TestAttributeRules(t *testing.T) {
+	dir := t.TempDir()
+	tfPath := filepath.Join(dir, "main.tf")
+	content := `resource "aws_s3_bucket" "logs" {
+  bucket = "Company_Logs"
+  tags = {
+    Name = "${var.env}-logs"
+  }
+}
+
+resource "aws_lambda_function" "api" {
+  function_name = local.name
+  tags = {
+    "Name" = "${var.env}-api"
+  }
+}
+`
+	if err := os.WriteFile(tfPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write tf: %v", err)
+	}
+	vars := "variable \"env\" {\n  default = \"QA\"\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "variables.tf"), []byte(vars), 0o644); err != nil {
+		t.Fatalf("write vars: %v", err)
+	}
+	cfgPath := filepath.Join(dir, "tfsuit.hcl")
+	cfgContent := `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+
+attribute "aws_s3_bucket" "bucket" {
+  pattern = "^[a-z0-9-]+$"
+}
+
+attribute "aws_lambda_function" "function_name" {
+  pattern = "^[a-z-]+$"
+}
+
+attribute "*" "tags[\"Name\"]" {
+  pattern = "^[a-z-]+$"
+}
+`
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	findings, err := parser.ParseFile(tfPath, cfg)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	// function_name comes from a local and is not resolved
+	if len(findings) != 3 {
+		t.Fatalf("want 3 findings, got %+v", findings)
+	}
+	bucket := findings[0]
+	if bucket.Kind != "attribute" || bucket.Line != 2 || bucket.Column != 12 || bucket.EndColumn != 26 {
+		t.Fatalf("unexpected bucket finding: %+v", bucket)
+	}
+	want := "bucket of aws_s3_bucket.logs 'Company_Logs' does not match pattern ^[a-z0-9-]+$"
+	if bucket.Message != want {
+		t.Fatalf("message = %q", bucket.Message)
+	}
+	if findings[1].Message != `tags["Name"] of aws_s3_bucket.logs 'QA-logs' does not match pattern ^[a-z-]+$` {
+		t.Fatalf("var default not resolved: %+v", findings[1])
+	}
+}
+
+func TestCheckTagsUsesProviderDefaults(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) string {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		return path
+	}
+	root := write("main.tf", `provider "aws" {
+  default_tags {
+    tags = {
+      Owner = "platform"
+    }
+  }
+}
+
+module "storage" {
+  source = "./modules/storage"
+}
+
+resource "aws_s3_bucket" "root" {
+  tags = {
+    Environment = "test"
+    cost_center = "42"
+  }
+}
+
+resource "aws_s3_bucket" "merged" {
+  tags = merge(local.common, { Environment = "dev" })
+}
+`)
+	child := write("modules/storage/main.tf", `resource "aws_s3_bucket" "logs" {}
+
+resource "aws_iam_role_policy_attachment" "attach" {}
+`)
+	cfgPath := write("tfsuit.hcl", `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+
+tags {
+  required      = ["Environment", "Owner", "CostCenter"]
+  key_case      = "PascalCase"
+  values        = { Environment = "^(dev|stg|prd)$" }
+  types         = ["aws_*"]
+  exclude_types = ["aws_iam_*"]
+}
+`)
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	files := []string{root, child}
+	cfgs := map[string]*config.Config{root: cfg, child: cfg}
+	findings, issues := parser.CheckTags(files, cfgs)
+
+	var msgs []string
+	for _, f := range findings {
+		msgs = append(msgs, f.Message)
+	}
+	want := []string{
+		"tag Environment of aws_s3_bucket.root is 'test', expected ^(dev|stg|prd)$",
+		"tag key 'cost_center' of aws_s3_bucket.root is not PascalCase",
+		"resource aws_s3_bucket.root is missing required tags: CostCenter",
+		// Owner comes from the default_tags of the provider inherited from the root module
+		"resource aws_s3_bucket.logs is missing required tags: Environment, CostCenter",
+	}
+	if strings.Join(msgs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(msgs, "\n"))
+	}
+	if len(issues) != 2 || issues[1].Address != "aws_s3_bucket.logs" {
+		t.Fatalf("unexpected issues: %+v", issues)
+	}
+}
+
+func TestCheckProviders(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) string {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		return path
+	}
+	root := write("main.tf", `provider "aws" {
+  alias = "virginia"
+}
+
+module "network" {
+  source = "./modules/network"
+  providers = {
+    aws.east = aws.virginia
+    aws.west = aws.oregon
+  }
+}
+
+resource "aws_s3_bucket" "logs" {
+  provider = aws.ohio
+}
+`)
+	child := write("modules/network/main.tf", `terraform {
+  required_providers {
+    aws = {
+      source                = "hashicorp/aws"
+      configuration_aliases = [aws.primary, aws.east]
+    }
+  }
+}
+
+resource "aws_vpc" "main" {
+  provider = aws.primary
+}
+
+data "aws_region" "current" {
+  provider = aws.secondary
+}
+`)
+	check := func(chain string) []string {
+		cfgPath := filepath.Join(dir, "tfsuit.hcl")
+		if err := os.WriteFile(cfgPath, []byte(`
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`+chain), 0o644); err != nil {
+			t.Fatalf("write cfg: %v", err)
+		}
+		cfg, err := config.Load(cfgPath)
+		if err != nil {
+			t.Fatalf("load cfg: %v", err)
+		}
+		var msgs []string
+		cfgs := map[string]*config.Config{root: cfg, child: cfg}
+		for _, f := range parser.CheckProviders([]string{root, child}, cfgs, modules.NewResolver(dir, "")) {
+			msgs = append(msgs, fmt.Sprintf("%d:%d %s", f.Line, f.Column, f.Message))
+		}
+		return msgs
+	}
+
+	if msgs := check(""); len(msgs) != 0 {
+		t.Fatalf("provider_chain is opt-in, got:\n%s", strings.Join(msgs, "\n"))
+	}
+	if msgs := check("provider_chain {\n  references = true\n}\n"); len(msgs) != 2 {
+		t.Fatalf("references only should report the 2 provider = findings, got:\n%s", strings.Join(msgs, "\n"))
+	}
+	msgs := check("provider_chain {\n  mappings   = true\n  references = true\n}\n")
+	want := []string{
+		"9:5 module 'network' maps aws.west to aws.oregon, which is not defined or passed into this module",
+		"9:5 module 'network' passes aws.west, which the module does not declare in configuration_aliases",
+		"5:1 module 'network' does not pass provider aws.primary, required by its configuration_aliases",
+		"14:14 aws_s3_bucket.logs uses provider aws.ohio, which is not defined or passed into this module",
+		"15:14 data.aws_region.current uses provider aws.secondary, which is not defined or passed into this module",
+	}
+	if strings.Join(msgs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(msgs, "\n"))
+	}
+}
+
+func TestCheckProvidersLeavesEmptyCallsToRequireProvider(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "main.tf")
+	child := filepath.Join(dir, "modules", "app", "main.tf")
+	if err := os.MkdirAll(filepath.Dir(child), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(root, []byte("module \"app\" {\n  source = \"./modules/app\"\n}\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(child, []byte(`terraform {
+  required_providers {
+    aws = {
+      source                = "hashicorp/aws"
+      configuration_aliases = [aws.east]
+    }
+  }
+}
+`), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cfgPath := filepath.Join(dir, "tfsuit.hcl")
+	if err := os.WriteFile(cfgPath, []byte(`
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules {
+  pattern          = ".*"
+  require_provider = true
+}
+resources { pattern = ".*" }
+provider_chain {
+  mappings = true
+}
+`), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	cfgs := map[string]*config.Config{root: cfg, child: cfg}
+	// la llamada sin providers ya la reporta require_provider
+	if findings := parser.CheckProviders([]string{root, child}, cfgs, modules.NewResolver(dir, "")); len(findings) != 0 {
+		t.Fatalf("expected no duplicate finding, got %+v", findings)
+	}
+}
+
+func TestCheckModuleCalls(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) string {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		return path
+	}
+	root := write("main.tf", `module "app" {
+  source       = "./modules/app"
+  count        = 1
+  InstanceType = "t3.micro"
+}
+
+module "remote" {
+  source = "org/app/aws"
+  whatever = true
+}
+`)
+	write("modules/app/variables.tf", `variable "instance_type" {}
+
+variable "name" {}
+
+variable "tags" {
+  default = {}
+}
+`)
+	var msgs []string
+	for _, f := range parser.CheckModuleCalls([]string{root}, modules.NewResolver(dir, "")) {
+		msgs = append(msgs, fmt.Sprintf("%d:%d %s", f.Line, f.Column, f.Message))
+	}
+	want := []string{
+		"4:3 module 'app' passes 'InstanceType', but ./modules/app declares no such variable",
+		"1:1 module 'app' does not set required variables of ./modules/app: instance_type, name",
+	}
+	if strings.Join(msgs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(msgs, "\n"))
+	}
+}
+
+func TestCheckTargetsAndChecks(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) string {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		return path
+	}
+	root := write("main.tf", `resource "aws_s3_bucket" "logs" {}
+
+module "net" {
+  source = "./net"
+}
+
+moved {
+  from = aws_s3_bucket.old
+  to   = aws_s3_bucket.logs
+}
+
+moved {
+  from = aws_s3_bucket.tmp
+  to   = aws_s3_bucket.archive
+}
+
+import {
+  to = module.net.aws_subnet.private["a"]
+  id = "subnet-1"
+}
+
+import {
+  to = module.vpc.aws_vpc.this
+  id = "vpc-1"
+}
+
+check "Bucket-Health" {
+  assert {
+    condition     = aws_s3_bucket.logs.bucket != ""
+    error_message = "bucket"
+  }
+}
+`)
+	child := write("net/main.tf", `resource "aws_subnet" "public" {}
+`)
+	var msgs []string
+	findings, err := parser.CheckTargets([]string{root, child}, modules.NewResolver(dir, ""))
+	if err != nil {
+		t.Fatalf("check targets: %v", err)
+	}
+	for _, f := range findings {
+		msgs = append(msgs, fmt.Sprintf("%d [%s] %s", f.Line, f.Kind, f.Message))
+	}
+	want := []string{
+		"14 [moved] moved target 'aws_s3_bucket.archive' is not declared",
+		"18 [import] import target 'module.net.aws_subnet.private' is not declared",
+		"23 [import] import target 'module.vpc.aws_vpc.this' is not declared",
+	}
+	if strings.Join(msgs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(msgs, "\n"))
+	}
+
+	cfgPath := write("tfsuit.hcl", `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   {
+  pattern          = ".*"
+  require_provider = false
+}
+resources { pattern = ".*" }
+checks    { pattern = "^[a-z0-9_]+$" }
+`)
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	res, err := parser.ParseFile(root, cfg)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(res) != 1 || res[0].Kind != "check" || res[0].Name != "Bucket-Health" {
+		t.Fatalf("expected a check naming finding, got %v", res)
+	}
+}
+
+func TestInstanceKeys(t *testing.T) {
+	dir := t.TempDir()
+	tfPath := filepath.Join(dir, "main.tf")
+	content := `resource "aws_instance" "web" {
+  for_each = {
+    "Web-1" = "t3.micro"
+    web_2   = "t3.small"
+    legacy  = "t2.micro"
+  }
+}
+
+module "dns" {
+  source   = "./dns"
+  for_each = toset(["public", "Private", var.extra])
+}
+
+resource "aws_subnet" "private" {
+  count      = length(var.azs)
+  cidr_block = var.cidrs[count.index]
+}
+
+resource "aws_eip" "nat" {
+  count = var.enabled ? 1 : 0
+}
+
+resource "aws_route53_record" "all" {
+  for_each = { for r in var.records : r.name => r }
+}
+`
+	if err := os.WriteFile(tfPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write tf: %v", err)
+	}
+	cfgPath := filepath.Join(dir, "tfsuit.hcl")
+	cfgContent := `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   {
+  pattern          = ".*"
+  require_provider = false
+}
+resources { pattern = ".*" }
+
+instance_keys {
+  pattern         = "^[a-z0-9_]+$"
+  ignore_exact    = ["legacy"]
+  prefer_for_each = true
+}
+`
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	findings, err := parser.ParseFile(tfPath, cfg)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var got []string
+	for _, f := range findings {
+		if f.Kind == "instance_key" {
+			got = append(got, fmt.Sprintf("%d %s: %s", f.Line, f.Name, f.Message))
+		}
+	}
+	want := []string{
+		`3 aws_instance.web["Web-1"]: instance key 'Web-1' does not match pattern ^[a-z0-9_]+$`,
+		`11 module.dns["Private"]: instance key 'Private' does not match pattern ^[a-z0-9_]+$`,
+		`15 aws_subnet.private: aws_subnet.private sets count = length(var.azs); for_each keeps instance keys stable when the list changes`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("instance key findings:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestHygieneChecks(t *testing.T) {
+	dir := t.TempDir()
+	tfPath := filepath.Join(dir, "main.tf")
+	content := `variable "region" {
+  description = "AWS region"
+  type        = string
+}
+
+variable "db_password" {
+  description = "database password"
+  type        = string
+  sensitive   = false
+}
+
+variable "api_token" {}
+
+output "endpoint" {
+  value = "x"
+}
+`
+	if err := os.WriteFile(tfPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write tf: %v", err)
+	}
+	cfgPath := filepath.Join(dir, "tfsuit.hcl")
+	cfgContent := `
+variables {
+  pattern               = ".*"
+  require_description   = true
+  require_type          = true
+  require_sensitive_for = ".*(password|secret|token).*"
+  description_pattern   = "^[A-Z]"
+}
+outputs {
+  pattern             = ".*"
+  require_description = true
+}
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	findings, err := parser.ParseFile(tfPath, cfg)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var msgs []string
+	for _, f := range findings {
+		msgs = append(msgs, f.Message)
+	}
+	want := []string{
+		"variable 'db_password' description does not match pattern ^[A-Z]",
+		"variable 'db_password' must be marked sensitive = true",
+		"variable 'api_token' has no description",
+		"variable 'api_token' has no type",
+		"variable 'api_token' must be marked sensitive = true",
+		"output 'endpoint' has no description",
+	}
+	if strings.Join(msgs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(msgs, "\n"))
+	}
+}
+
+func TestCheckUnused(t *testing.T) {
+	dir := t.TempDir()
+	tfPath := filepath.Join(dir, "main.tf")
+	content := `variable "region" {}
+
+variable "name" {}
+
+locals {
+  dead = 1
+}
+
+data "aws_ami" "ubuntu" {}
+
+output "name" {
+  value = var.name
+}
+`
+	if err := os.WriteFile(tfPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write tf: %v", err)
+	}
+	cfgPath := filepath.Join(dir, "tfsuit.hcl")
+	cfgContent := `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+
+unused {
+  variables = true
+  locals    = true
+}
+`
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	findings, err := parser.CheckUnused([]string{tfPath}, map[string]*config.Config{tfPath: cfg})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	var msgs []string
+	for _, f := range findings {
+		msgs = append(msgs, fmt.Sprintf("%d [%s] %s", f.Line, f.Kind, f.Message))
+	}
+	// data sources are not checked: unused.data is off
+	want := "1 [unused] variable 'region' is never used in its module\n6 [unused] local 'dead' is never used in its module"
+	if strings.Join(msgs, "\n") != want {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(msgs, "\n"))
+	}
+}
+
+func TestLayoutFindings(t *testing.T) {
+	dir := t.TempDir()
+	tfPath := filepath.Join(dir, "main.tf")
+	content := `terraform {}
+
+provider "aws" {
+  alias = "east"
+}
+
+variable "region" {}
+
+resource "aws_s3_bucket" "logs" {}
+`
+	if err := os.WriteFile(tfPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write tf: %v", err)
+	}
+	cfgPath := filepath.Join(dir, "tfsuit.hcl")
+	cfgContent := `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+
+layout {
+  variable  = ["variables.tf"]
+  provider  = ["providers.tf", "providers_*.tf"]
+  terraform = ["versions.tf"]
+  resource  = ["main.tf", "*_resources.tf"]
+}
+`
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	findings, err := parser.ParseFile(tfPath, cfg)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var msgs []string
+	for _, f := range findings {
+		if f.Kind == "layout" {
+			msgs = append(msgs, fmt.Sprintf("%d %s", f.Line, f.Message))
+		}
+	}
+	want := []string{
+		"1 terraform is declared in main.tf, expected versions.tf",
+		"3 provider 'aws.east' is declared in main.tf, expected providers.tf or providers_*.tf",
+		"7 variable 'region' is declared in main.tf, expected variables.tf",
+	}
+	if strings.Join(msgs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(msgs, "\n"))
+	}
+}
+
+func TestOrderingFindings(t *testing.T) {
+	dir := t.TempDir()
+	tfPath := filepath.Join(dir, "main.tf")
+	content := `variable "zone" {}
+
+variable "region" {}
+
+resource "aws_instance" "web" {
+  ami   = "x"
+  count = 2
+}
+
+module "net" {
+  source   = "./net"
+  for_each = var.nets
+}
+
+output "b" {
+  value = 1
+}
+
+output "a" {
+  value = 2
+}
+`
+	if err := os.WriteFile(tfPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write tf: %v", err)
+	}
+	cfgPath := filepath.Join(dir, "tfsuit.hcl")
+	cfgContent := `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules {
+  pattern          = ".*"
+  require_provider = false
+}
+resources { pattern = ".*" }
+
+ordering {
+  sort           = ["variable"]
+  meta_arguments = "first"
+}
+`
+	if err := os.WriteFile(cfgPath, []byte(cfgContent), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	findings, err := parser.ParseFile(tfPath, cfg)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var msgs []string
+	for _, f := range findings {
+		if f.Kind == "ordering" {
+			msgs = append(msgs, fmt.Sprintf("%d %s", f.Line, f.Message))
+		}
+	}
+	// outputs are not sorted; the module keeps source on top
+	want := []string{
+		"3 variable 'region' is out of alphabetical order (after 'zone')",
+		"5 resource 'aws_instance.web' should list its meta-arguments first (count, for_each, provider, providers, depends_on, lifecycle)",
+	}
+	if strings.Join(msgs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected findings:\n%s", strings.Join(msgs, "\n"))
+	}
+}
@@ -0,0 +1,252 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/model"
+	"github.com/josdagaro/tfsuit/internal/modules"
+)
+
+// providerModule is what a module directory declares about providers.
+type providerModule struct {
+	defined     map[string]bool // provider blocks: aws, aws.west
+	aliases     map[string]bool // required_providers configuration_aliases
+	required    map[string]bool // required_providers local names
+	hasRequired bool
+}
+
+// available reports whether ref can be used inside the module: default providers always can,
+// aliases only when a provider block defines them or configuration_aliases receives them.
+func (m *providerModule) available(ref string) bool {
+	return !strings.Contains(ref, ".") || m.defined[ref] || m.aliases[ref]
+}
+
+type providerMapping struct {
+	Key, Value string // child ref = parent ref
+	Range      hcl.Range
+}
+
+type providerCall struct {
+	path     string
+	dir      string
+	child    string
+	block    *hclsyntax.Block
+	mappings []providerMapping
+}
+
+type providerUse struct {
+	path    string
+	dir     string
+	address string
+	ref     string
+	rng     hcl.Range
+}
+
+// CheckProviders follows providers through module calls and reports mappings that don't
+// match the child's configuration_aliases (missing, extra) or pass an alias the caller doesn't
+// have (unknown), plus provider = references to aliases not available in their module. Each
+// file's provider_chain block decides which of the two groups it gets.
+func CheckProviders(files []string, cfgs map[string]*config.Config, res *modules.Resolver) []model.Finding {
+	enabled := false
+	for _, c := range cfgs {
+		if c.ProviderChain.MappingsEnabled() || c.ProviderChain.ReferencesEnabled() {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return nil
+	}
+	mods := map[string]*providerModule{}
+	var calls []providerCall
+	var uses []providerUse
+
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			continue
+		}
+		body := file.Body.(*hclsyntax.Body)
+		dir := filepath.Dir(path)
+		mod := mods[dir]
+		if mod == nil {
+			mod = &providerModule{defined: map[string]bool{}, aliases: map[string]bool{}, required: map[string]bool{}}
+			mods[dir] = mod
+		}
+
+		for _, b := range body.Blocks {
+			switch b.Type {
+			case "provider":
+				if len(b.Labels) == 0 {
+					continue
+				}
+				ref := b.Labels[0]
+				if attr, ok := b.Body.Attributes["alias"]; ok {
+					if alias, ok := literalString(attr.Expr, nil); ok {
+						ref += "." + alias
+					}
+				}
+				mod.defined[ref] = true
+
+			case "terraform":
+				for _, rp := range b.Body.Blocks {
+					if rp.Type != "required_providers" {
+						continue
+					}
+					mod.hasRequired = true
+					for name, attr := range rp.Body.Attributes {
+						mod.required[name] = true
+						for _, ref := range modules.ConfigurationAliases(attr.Expr) {
+							mod.aliases[ref] = true
+						}
+					}
+				}
+
+			case "module":
+				if len(b.Labels) == 0 {
+					continue
+				}
+				attr, ok := b.Body.Attributes["source"]
+				if !ok {
+					continue
+				}
+				source, ok := literalString(attr.Expr, nil)
+				if !ok {
+					continue
+				}
+				child, ok := res.Resolve(dir, b.Labels[0], source)
+				if !ok {
+					continue
+				}
+				calls = append(calls, providerCall{
+					path:     path,
+					dir:      dir,
+					child:    child,
+					block:    b,
+					mappings: providerMappings(b),
+				})
+
+			case "resource", "data":
+				if len(b.Labels) < 2 {
+					continue
+				}
+				attr, ok := b.Body.Attributes["provider"]
+				if !ok {
+					continue
+				}
+				address := b.Labels[0] + "." + b.Labels[1]
+				if b.Type == "data" {
+					address = "data." + address
+				}
+				uses = append(uses, providerUse{path: path, dir: dir, address: address, ref: modules.ProviderRef(attr.Expr), rng: attr.Expr.Range()})
+			}
+		}
+	}
+
+	var findings []model.Finding
+	add := func(path, name string, rng hcl.Range, msg string) {
+		findings = append(findings, model.Finding{
+			File:    path,
+			Line:    rng.Start.Line,
+			Column:  rng.Start.Column,
+			Kind:    "provider",
+			Name:    name,
+			Message: msg,
+		})
+	}
+
+	for _, call := range calls {
+		cfg := cfgs[call.path]
+		if cfg == nil || !cfg.ProviderChain.MappingsEnabled() {
+			continue
+		}
+		name := call.block.Labels[0]
+		parent := mods[call.dir]
+		passed := map[string]bool{}
+		for _, m := range call.mappings {
+			passed[m.Key] = true
+			if !parent.available(m.Value) {
+				add(call.path, name, m.Range, fmt.Sprintf("module '%s' maps %s to %s, which is not defined or passed into this module", name, m.Key, m.Value))
+			}
+		}
+		child, ok := mods[call.child]
+		if !ok {
+			// módulo remoto (.terraform/modules, vendor): sólo interesa su interfaz
+			mi, err := modules.Read(call.child)
+			if err != nil {
+				continue
+			}
+			child = &providerModule{aliases: map[string]bool{}, required: mi.Providers, hasRequired: len(mi.Providers) > 0}
+			for _, ref := range mi.Aliases {
+				child.aliases[ref] = true
+			}
+			mods[call.child] = child
+		}
+		for _, m := range call.mappings {
+			switch {
+			case strings.Contains(m.Key, ".") && !child.aliases[m.Key]:
+				add(call.path, name, m.Range, fmt.Sprintf("module '%s' passes %s, which the module does not declare in configuration_aliases", name, m.Key))
+			case !strings.Contains(m.Key, ".") && child.hasRequired && !child.required[m.Key]:
+				add(call.path, name, m.Range, fmt.Sprintf("module '%s' passes %s, which the module does not declare in required_providers", name, m.Key))
+			}
+		}
+		var missing []string
+		for ref := range child.aliases {
+			if !passed[ref] {
+				missing = append(missing, ref)
+			}
+		}
+		sort.Strings(missing)
+		if len(call.mappings) == 0 && cfg.Modules != nil && cfg.Modules.RequiresProvider() {
+			continue // ya reportado: must declare at least one providers mapping
+		}
+		for _, ref := range missing {
+			add(call.path, name, call.block.DefRange(), fmt.Sprintf("module '%s' does not pass provider %s, required by its configuration_aliases", name, ref))
+		}
+	}
+
+	for _, u := range uses {
+		if cfg := cfgs[u.path]; cfg == nil || !cfg.ProviderChain.ReferencesEnabled() {
+			continue
+		}
+		if u.ref == "" || mods[u.dir].available(u.ref) {
+			continue
+		}
+		add(u.path, u.address, u.rng, fmt.Sprintf("%s uses provider %s, which is not defined or passed into this module", u.address, u.ref))
+	}
+	return findings
+}
+
+// providerMappings reads the providers = { child = parent } map of a module call.
+func providerMappings(block *hclsyntax.Block) []providerMapping {
+	attr, ok := block.Body.Attributes["providers"]
+	if !ok {
+		return nil
+	}
+	obj, ok := attr.Expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return nil
+	}
+	var out []providerMapping
+	for _, item := range obj.Items {
+		keyExpr := item.KeyExpr
+		if k, ok := keyExpr.(*hclsyntax.ObjectConsKeyExpr); ok {
+			keyExpr = k.Wrapped
+		}
+		if key, val := modules.ProviderRef(keyExpr), modules.ProviderRef(item.ValueExpr); key != "" && val != "" {
+			out = append(out, providerMapping{Key: key, Value: val, Range: hcl.RangeBetween(item.KeyExpr.Range(), item.ValueExpr.Range())})
+		}
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/model"
+)
+
+// SpacingIssue is a gap between neighbouring blocks, or items of a block body, that breaks
+// block_spacing.
+type SpacingIssue struct {
+	Line    int    // first line of the next block or item
+	Insert  int    // where missing blank lines go: above the comments of the next block or item
+	Missing int    // blank lines to add
+	Extra   []int  // blank lines to drop, beyond max_blank_lines
+	Name    string // kinds of both neighbours, "module/module"
+	Message string
+}
+
+// SpacingIssues checks the gaps between top-level blocks of every type and, with
+// block_spacing.nested, around the nested blocks inside them. max_blank_lines applies to every
+// gap between blocks and between block items.
+func SpacingIssues(src []byte, body *hclsyntax.Body, spacing *config.BlockSpacing) []SpacingIssue {
+	if spacing == nil || !spacing.EnabledValue() {
+		return nil
+	}
+	lines, comments := splitLines(src), CommentLines(src)
+	var issues []SpacingIssue
+	for i := 1; i < len(body.Blocks); i++ {
+		prev, next := body.Blocks[i-1], body.Blocks[i]
+		min := spacing.MinLines()
+		if spacing.AllowCompactKind(prev.Type) && spacing.AllowCompactKind(next.Type) && singleLine(prev) && singleLine(next) {
+			min = 0
+		}
+		gap := checkGap(lines, comments, prev.Range().End.Line, next.Range().Start.Line, min, spacing.MaxLines())
+		if gap.ok() {
+			continue
+		}
+		gap.Name = prev.Type + "/" + next.Type
+		gap.Message = gap.explain(min, spacing.MaxLines(), describeBlock(prev), describeBlock(next), "")
+		issues = append(issues, gap)
+	}
+	for _, b := range body.Blocks {
+		issues = append(issues, nestedSpacing(lines, comments, b, describeBlock(b), spacing)...)
+	}
+	return issues
+}
+
+// nestedSpacing checks the gaps between the items of block, and of the blocks nested in it.
+func nestedSpacing(lines []string, comments map[int]bool, block *hclsyntax.Block, owner string, spacing *config.BlockSpacing) []SpacingIssue {
+	var issues []SpacingIssue
+	items := BodyItems(block.Body)
+	for i := 1; i < len(items); i++ {
+		prev, next := items[i-1], items[i]
+		min := 0
+		for _, it := range []BodyItem{prev, next} {
+			if !it.Block {
+				continue
+			}
+			if n, ok := spacing.NestedMin(it.Name); ok && n > min {
+				min = n
+			}
+		}
+		gap := checkGap(lines, comments, prev.Range.End.Line, next.Range.Start.Line, min, spacing.MaxLines())
+		if gap.ok() {
+			continue
+		}
+		gap.Name = prev.Name + "/" + next.Name
+		gap.Message = gap.explain(min, spacing.MaxLines(), prev.describe(), next.describe(), owner)
+		issues = append(issues, gap)
+	}
+	for _, b := range block.Body.Blocks {
+		issues = append(issues, nestedSpacing(lines, comments, b, owner, spacing)...)
+	}
+	return issues
+}
+
+// checkGap counts the blank lines between endLine and startLine against min and max (0: no max).
+func checkGap(lines []string, comments map[int]bool, endLine, startLine, min, max int) SpacingIssue {
+	gap := SpacingIssue{Line: startLine, Insert: startLine}
+	var blank []int
+	for n := endLine + 1; n < startLine && n <= len(lines); n++ {
+		if strings.TrimSpace(lines[n-1]) == "" {
+			blank = append(blank, n)
+		}
+	}
+	if len(blank) < min {
+		gap.Missing = min - len(blank)
+		// los comentarios justo encima del bloque siguen pegados a él
+		gap.Insert = CommentStart(comments, startLine, endLine)
+	}
+	if max > 0 && len(blank) > max {
+		gap.Extra = blank[max:]
+	}
+	return gap
+}
+
+func (g SpacingIssue) ok() bool {
+	return g.Missing == 0 && len(g.Extra) == 0
+}
+
+func (g SpacingIssue) explain(min, max int, prev, next, owner string) string {
+	where := ""
+	if owner != "" {
+		where = " in " + owner
+	}
+	if g.Missing > 0 {
+		return fmt.Sprintf("expected at least %d blank line(s) between %s and %s%s", min, prev, next, where)
+	}
+	return fmt.Sprintf("expected at most %d blank line(s) between %s and %s%s", max, prev, next, where)
+}
+
+// lineColumn is the column of the first non-blank character of line (1-based).
+func lineColumn(src []byte, line int) int {
+	lines := strings.Split(string(src), "\n")
+	if line < 1 || line > len(lines) {
+		return 1
+	}
+	text := lines[line-1]
+	return len(text) - len(strings.TrimLeft(text, " \t")) + 1
+}
+
+func checkBlockSpacing(path string, src []byte, body *hclsyntax.Body, spacing *config.BlockSpacing) []model.Finding {
+	var findings []model.Finding
+	for _, issue := range SpacingIssues(src, body, spacing) {
+		findings = append(findings, model.Finding{
+			File:    path,
+			Line:    issue.Line,
+			Column:  lineColumn(src, issue.Line),
+			Kind:    "spacing",
+			Name:    issue.Name,
+			Message: issue.Message,
+		})
+	}
+	return findings
+}
+
+func singleLine(b *hclsyntax.Block) bool {
+	rng := b.Range()
+	return rng.Start.Line == rng.End.Line
+}
+
+// describeBlock names a block in spacing messages: module 'a', resource 'web', locals.
+func describeBlock(b *hclsyntax.Block) string {
+	if len(b.Labels) == 0 {
+		return b.Type
+	}
+	return fmt.Sprintf("%s '%s'", b.Type, b.Labels[len(b.Labels)-1])
+}
@@ -0,0 +1,300 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/model"
+	"github.com/josdagaro/tfsuit/internal/modules"
+)
+
+// TagIssue is a resource missing required tags; fix inserts the ones mapped to variables.
+type TagIssue struct {
+	File    string
+	Address string // TYPE.NAME
+	Missing []string
+}
+
+type tagEntry struct {
+	Key   string
+	Value hclsyntax.Expression
+	Range hcl.Range
+}
+
+// tagSet is what a tags expression is known to contain; complete is false when part of it
+// can't be read statically (var.tags, merge(local.common, …)).
+type tagSet struct {
+	entries  []tagEntry
+	complete bool
+}
+
+func (s tagSet) has(key string) bool {
+	for _, e := range s.entries {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+type tagsModule struct {
+	defaults map[string]tagSet // provider ref (aws, aws.west) → default_tags
+	vars     *moduleVars
+}
+
+type tagsCall struct {
+	parent    string
+	providers map[string]string // child ref → parent ref
+}
+
+type tagsResource struct {
+	path  string
+	dir   string
+	block *hclsyntax.Block
+}
+
+// CheckTags applies the tags policy of each file's config to its resources: required keys
+// (less the provider default_tags that reach the resource, through module calls if needed),
+// key case and value patterns.
+func CheckTags(files []string, cfgs map[string]*config.Config) ([]model.Finding, []TagIssue) {
+	enabled := false
+	for _, c := range cfgs {
+		if c.Tags != nil {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	mods := map[string]*tagsModule{}
+	callers := map[string][]tagsCall{} // child dir → calls
+	var resources []tagsResource
+	var findings []model.Finding
+
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			continue
+		}
+		body := file.Body.(*hclsyntax.Body)
+		dir := filepath.Dir(path)
+		mod := mods[dir]
+		if mod == nil {
+			mod = &tagsModule{defaults: map[string]tagSet{}, vars: &moduleVars{path: path, body: body}}
+			mods[dir] = mod
+		}
+		policy := cfgs[path].Tags
+
+		for _, b := range body.Blocks {
+			switch b.Type {
+			case "provider":
+				if len(b.Labels) == 0 {
+					continue
+				}
+				ref := b.Labels[0]
+				if attr, ok := b.Body.Attributes["alias"]; ok {
+					if alias, ok := literalString(attr.Expr, nil); ok {
+						ref += "." + alias
+					}
+				}
+				set := tagSet{complete: true}
+				for _, db := range b.Body.Blocks {
+					if db.Type != "default_tags" {
+						continue
+					}
+					if attr, ok := db.Body.Attributes["tags"]; ok {
+						set = readTags(attr.Expr)
+						if policy != nil {
+							findings = append(findings, checkTagEntries(path, "provider "+ref+" default_tags", set, policy, mod.vars)...)
+						}
+					}
+				}
+				mod.defaults[ref] = set
+
+			case "module":
+				attr, ok := b.Body.Attributes["source"]
+				if !ok {
+					continue
+				}
+				source, ok := literalString(attr.Expr, nil)
+				if !ok || !modules.IsLocal(source) {
+					continue
+				}
+				call := tagsCall{parent: dir, providers: map[string]string{}}
+				for _, m := range providerMappings(b) {
+					call.providers[m.Key] = m.Value
+				}
+				child := filepath.Clean(filepath.Join(dir, source))
+				callers[child] = append(callers[child], call)
+
+			case "resource":
+				if len(b.Labels) == 2 && policy != nil {
+					resources = append(resources, tagsResource{path: path, dir: dir, block: b})
+				}
+			}
+		}
+	}
+
+	memo := map[string]tagSet{}
+	var defaultsFor func(dir, ref string, visiting map[string]bool) tagSet
+	defaultsFor = func(dir, ref string, visiting map[string]bool) tagSet {
+		key := dir + "|" + ref
+		if set, ok := memo[key]; ok {
+			return set
+		}
+		if visiting[key] {
+			return tagSet{}
+		}
+		visiting[key] = true
+		defer delete(visiting, key)
+
+		if mod := mods[dir]; mod != nil {
+			if set, ok := mod.defaults[ref]; ok {
+				memo[key] = set
+				return set
+			}
+		}
+		// inherited from the callers: only what every caller passes down is guaranteed
+		var out *tagSet
+		for _, call := range callers[dir] {
+			parentRef, ok := call.providers[ref]
+			if !ok {
+				if strings.Contains(ref, ".") {
+					continue // aliased providers are never inherited implicitly
+				}
+				parentRef = ref
+			}
+			set := defaultsFor(call.parent, parentRef, visiting)
+			if out == nil {
+				out = &set
+				continue
+			}
+			var both []tagEntry
+			for _, e := range out.entries {
+				if set.has(e.Key) {
+					both = append(both, e)
+				}
+			}
+			out = &tagSet{entries: both, complete: out.complete && set.complete}
+		}
+		if out == nil {
+			out = &tagSet{complete: true}
+		}
+		memo[key] = *out
+		return *out
+	}
+
+	var issues []TagIssue
+	for _, r := range resources {
+		policy := cfgs[r.path].Tags
+		typ, name := r.block.Labels[0], r.block.Labels[1]
+		if policy.Excludes(typ) {
+			continue
+		}
+		address := typ + "." + name
+		set := tagSet{complete: true}
+		attr, hasTags := r.block.Body.Attributes[policy.TagAttribute()]
+		if hasTags {
+			set = readTags(attr.Expr)
+		} else if !policy.Requires(typ) {
+			continue
+		}
+		findings = append(findings, checkTagEntries(r.path, address, set, policy, mods[r.dir].vars)...)
+		if !set.complete {
+			continue
+		}
+
+		ref := strings.SplitN(typ, "_", 2)[0]
+		if attr, ok := r.block.Body.Attributes["provider"]; ok {
+			if p := modules.ProviderRef(attr.Expr); p != "" {
+				ref = p
+			}
+		}
+		defaults := defaultsFor(r.dir, ref, map[string]bool{})
+		var missing []string
+		for _, key := range policy.Required {
+			if set.has(key) || defaults.has(key) {
+				continue
+			}
+			missing = append(missing, key)
+		}
+		if len(missing) == 0 || !defaults.complete {
+			continue
+		}
+		issues = append(issues, TagIssue{File: r.path, Address: address, Missing: missing})
+		rng := nameRange(r.block)
+		findings = append(findings, model.Finding{
+			File:    r.path,
+			Line:    rng.Start.Line,
+			Column:  rng.Start.Column,
+			Kind:    "tags",
+			Name:    address,
+			Message: fmt.Sprintf("resource %s is missing required tags: %s", address, strings.Join(missing, ", ")),
+		})
+	}
+	return findings, issues
+}
+
+// readTags reads a tags expression: an object, or merge() of objects and other values.
+func readTags(expr hclsyntax.Expression) tagSet {
+	switch e := expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		set := tagSet{complete: true}
+		for _, item := range e.Items {
+			key, ok := objectKey(item.KeyExpr)
+			if !ok {
+				set.complete = false
+				continue
+			}
+			set.entries = append(set.entries, tagEntry{Key: key, Value: item.ValueExpr, Range: item.KeyExpr.Range()})
+		}
+		return set
+	case *hclsyntax.FunctionCallExpr:
+		if e.Name != "merge" {
+			break
+		}
+		set := tagSet{complete: true}
+		for _, arg := range e.Args {
+			part := readTags(arg)
+			set.entries = append(set.entries, part.entries...)
+			set.complete = set.complete && part.complete
+		}
+		return set
+	}
+	return tagSet{}
+}
+
+func checkTagEntries(path, owner string, set tagSet, policy *config.TagsPolicy, vars *moduleVars) []model.Finding {
+	var findings []model.Finding
+	for _, e := range set.entries {
+		var msg string
+		if !policy.KeyOK(e.Key) {
+			msg = fmt.Sprintf("tag key '%s' of %s is not %s", e.Key, owner, policy.KeyCase)
+		} else if value, ok := literalString(e.Value, vars); ok && !policy.ValueOK(e.Key, value) {
+			msg = fmt.Sprintf("tag %s of %s is '%s', expected %s", e.Key, owner, value, policy.Values[e.Key])
+		}
+		if msg == "" {
+			continue
+		}
+		findings = append(findings, model.Finding{
+			File:    path,
+			Line:    e.Range.Start.Line,
+			Column:  e.Range.Start.Column,
+			Kind:    "tags",
+			Name:    owner,
+			Message: msg,
+		})
+	}
+	return findings
+}
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/model"
+	"github.com/josdagaro/tfsuit/internal/refs"
+)
+
+// CheckUnused reports the variables, locals and data sources that nothing references within
+// their module directory, for the kinds enabled in the unused block of each file's config.
+func CheckUnused(files []string, cfgs map[string]*config.Config) ([]model.Finding, error) {
+	enabled := false
+	for _, c := range cfgs {
+		if len(c.Unused.Kinds()) > 0 {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return nil, nil
+	}
+	ix, err := refs.Build(files)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, 0, len(ix.Modules))
+	for dir := range ix.Modules {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var findings []model.Finding
+	for _, dir := range dirs {
+		for _, d := range UnusedDecls(ix.Modules[dir], cfgs) {
+			name := UnusedName(d)
+			findings = append(findings, model.Finding{
+				File:    d.File,
+				Line:    d.Line,
+				Column:  d.Column,
+				Kind:    "unused",
+				Name:    name,
+				Message: fmt.Sprintf("%s '%s' is never used in its module", d.Kind, name),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// UnusedDecls returns the unused declarations of m whose kind the module's config checks.
+func UnusedDecls(m *refs.Module, cfgs map[string]*config.Config) []refs.Decl {
+	var kinds map[string]bool
+	for _, d := range m.Declared {
+		if c, ok := cfgs[d.File]; ok {
+			kinds = c.Unused.Kinds()
+			break
+		}
+	}
+	if len(kinds) == 0 {
+		return nil
+	}
+	return m.Unused(kinds)
+}
+
+// UnusedName is the name of d as shown in findings: the label, or TYPE.NAME for data sources.
+func UnusedName(d refs.Decl) string {
+	return strings.SplitN(d.Address, ".", 2)[1]
+}
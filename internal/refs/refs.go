@@ -0,0 +1,525 @@
+// Package refs indexes declarations and references of Terraform modules (one module per directory).
+package refs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Decl is a named declaration inside a module.
+type Decl struct {
+	Address string // var.x, local.x, output.x, module.x, data.t.x, t.x
+	Kind    string // variable, local, output, module, data, resource
+	File    string
+	Line    int
+	Column  int       // of the name label, or of the name for locals
+	Range   hcl.Range // the whole block, or the attribute for locals
+}
+
+// Ref is a traversal that points at a declaration.
+type Ref struct {
+	Address string // address of the referenced declaration
+	Output  string // for module.<call>.<output> references, the output name
+	File    string
+	Line    int
+	Range   hcl.Range
+}
+
+// Target is the `to` address of a moved or import block.
+type Target struct {
+	Block   string // moved or import
+	Address string // without index steps: module.net.aws_subnet.this
+	File    string
+	Line    int
+	Range   hcl.Range
+}
+
+// Module holds everything declared and referenced in one directory.
+type Module struct {
+	Dir      string
+	Declared map[string]Decl
+	Refs     []Ref
+	Targets  []Target
+	Calls    map[string]string   // module call name → source
+	Args     map[string][]string // module call name → arguments passed to the child
+}
+
+// Index groups modules by directory.
+type Index struct {
+	Modules map[string]*Module
+
+	// ResolveChild maps a module call (name and source) to the directory holding the child
+	// module. Without it module.<call>.<output> references are not checked against child outputs.
+	ResolveChild func(parentDir, name, source string) (string, bool)
+}
+
+// New returns an empty index.
+func New() *Index {
+	return &Index{Modules: map[string]*Module{}}
+}
+
+// Build reads and indexes paths; unparsable files are skipped.
+func Build(paths []string) (*Index, error) {
+	ix := New()
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		_ = ix.Add(path, src)
+	}
+	return ix, nil
+}
+
+func (ix *Index) module(dir string) *Module {
+	if m, ok := ix.Modules[dir]; ok {
+		return m
+	}
+	m := &Module{Dir: dir, Declared: map[string]Decl{}, Calls: map[string]string{}, Args: map[string][]string{}}
+	ix.Modules[dir] = m
+	return m
+}
+
+// Add indexes one file's source under path's directory.
+func (ix *Index) Add(path string, src []byte) error {
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return fmt.Errorf("%s: %s", path, diags.Error())
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return fmt.Errorf("unexpected body type in %s", path)
+	}
+	m := ix.module(filepath.Dir(path))
+
+	for _, b := range body.Blocks {
+		m.declare(path, b)
+		switch b.Type {
+		case "moved", "removed", "import":
+			// `from` names an address that is gone on purpose; `to` must exist.
+			if attr, ok := b.Body.Attributes["to"]; ok {
+				m.collectTarget(path, b.Type, attr.Expr)
+			}
+			if b.Type == "import" {
+				for name, attr := range b.Body.Attributes {
+					if name != "to" && name != "provider" {
+						m.collect(path, attr.Expr, nil)
+					}
+				}
+			}
+			continue
+		case "terraform":
+			continue
+		case "module":
+			if len(b.Labels) > 0 {
+				if attr, ok := b.Body.Attributes["source"]; ok {
+					if v, diags := attr.Expr.Value(nil); !diags.HasErrors() && v.Type() == cty.String && v.IsKnown() && !v.IsNull() {
+						m.Calls[b.Labels[0]] = v.AsString()
+					}
+				}
+				for name := range b.Body.Attributes {
+					switch name {
+					case "source", "version", "providers", "count", "for_each", "depends_on":
+						continue
+					}
+					m.Args[b.Labels[0]] = append(m.Args[b.Labels[0]], name)
+				}
+				sort.Strings(m.Args[b.Labels[0]])
+			}
+		}
+		m.collectBody(path, b.Body)
+	}
+	return nil
+}
+
+func (m *Module) declare(path string, b *hclsyntax.Block) {
+	add := func(addr, kind string, rng hcl.Range) {
+		col := rng.Start.Column
+		if n := len(b.LabelRanges); n > 0 && b.Type != "locals" {
+			col = b.LabelRanges[n-1].Start.Column
+		}
+		m.Declared[addr] = Decl{Address: addr, Kind: kind, File: path, Line: rng.Start.Line, Column: col, Range: rng}
+	}
+	switch b.Type {
+	case "variable":
+		if len(b.Labels) > 0 {
+			add("var."+b.Labels[0], "variable", b.Range())
+		}
+	case "output":
+		if len(b.Labels) > 0 {
+			add("output."+b.Labels[0], "output", b.Range())
+		}
+	case "module":
+		if len(b.Labels) > 0 {
+			add("module."+b.Labels[0], "module", b.Range())
+		}
+	case "resource":
+		if len(b.Labels) > 1 {
+			add(b.Labels[0]+"."+b.Labels[1], "resource", b.Range())
+		}
+	case "data":
+		if len(b.Labels) > 1 {
+			add("data."+b.Labels[0]+"."+b.Labels[1], "data", b.Range())
+		}
+	case "locals":
+		for name, attr := range b.Body.Attributes {
+			add("local."+name, "local", attr.SrcRange)
+		}
+	}
+}
+
+// collectBody gathers references from a block body, skipping meta-arguments that hold provider
+// or attribute names instead of references.
+func (m *Module) collectBody(path string, body *hclsyntax.Body) {
+	for name, attr := range body.Attributes {
+		switch name {
+		case "provider", "providers":
+			continue
+		}
+		m.collect(path, attr.Expr, nil)
+	}
+	for _, child := range body.Blocks {
+		switch child.Type {
+		case "lifecycle":
+			for name, attr := range child.Body.Attributes {
+				if name == "ignore_changes" {
+					continue
+				}
+				m.collect(path, attr.Expr, nil)
+			}
+			m.collectBody(path, &hclsyntax.Body{Blocks: child.Body.Blocks})
+		case "dynamic":
+			iterator := ""
+			if len(child.Labels) > 0 {
+				iterator = child.Labels[0]
+			}
+			if attr, ok := child.Body.Attributes["iterator"]; ok {
+				if tr, diags := hcl.AbsTraversalForExpr(attr.Expr); !diags.HasErrors() {
+					iterator = tr.RootName()
+				}
+			}
+			m.collectScoped(path, child.Body, iterator)
+		default:
+			m.collectBody(path, child.Body)
+		}
+	}
+}
+
+// collectScoped gathers references inside a dynamic block, where local names its iterator.
+func (m *Module) collectScoped(path string, body *hclsyntax.Body, local string) {
+	for _, attr := range body.Attributes {
+		m.collect(path, attr.Expr, map[string]bool{local: true})
+	}
+	for _, child := range body.Blocks {
+		m.collectScoped(path, child.Body, local)
+	}
+}
+
+// collect walks expr and records traversals, ignoring names bound by for expressions and iterators.
+func (m *Module) collect(path string, expr hclsyntax.Expression, bound map[string]bool) {
+	scoped := map[string]bool{}
+	for k := range bound {
+		scoped[k] = true
+	}
+	hclsyntax.VisitAll(expr, func(n hclsyntax.Node) hcl.Diagnostics {
+		if fe, ok := n.(*hclsyntax.ForExpr); ok {
+			if fe.KeyVar != "" {
+				scoped[fe.KeyVar] = true
+			}
+			scoped[fe.ValVar] = true
+		}
+		return nil
+	})
+	hclsyntax.VisitAll(expr, func(n hclsyntax.Node) hcl.Diagnostics {
+		st, ok := n.(*hclsyntax.ScopeTraversalExpr)
+		if !ok {
+			return nil
+		}
+		if ref, ok := refFromTraversal(st.Traversal, scoped); ok {
+			ref.File = path
+			ref.Line = st.SrcRange.Start.Line
+			ref.Range = st.SrcRange
+			m.Refs = append(m.Refs, ref)
+		}
+		return nil
+	})
+}
+
+// collectTarget records the `to` address of a moved or import block as a reference to the
+// module call or resource it starts with.
+func (m *Module) collectTarget(path, block string, expr hclsyntax.Expression) {
+	tr, ok := Address(expr)
+	if !ok {
+		return
+	}
+	names := AttrNames(tr)
+	if len(names) < 2 {
+		return
+	}
+	ref := Ref{Address: names[0] + "." + names[1], File: path, Line: expr.Range().Start.Line, Range: expr.Range()}
+	if names[0] == "data" && len(names) > 2 {
+		ref.Address = "data." + names[1] + "." + names[2]
+	}
+	m.Refs = append(m.Refs, ref)
+	m.Targets = append(m.Targets, Target{Block: block, Address: strings.Join(names, "."), File: path, Line: ref.Line, Range: ref.Range})
+}
+
+// Address returns the root and attribute steps of the address in a moved, import or removed
+// block, dropping index steps: module.net["a"].aws_subnet.this[each.key] gives
+// module.net.aws_subnet.this.
+func Address(expr hclsyntax.Expression) (hcl.Traversal, bool) {
+	switch e := expr.(type) {
+	case *hclsyntax.ScopeTraversalExpr:
+		return attrSteps(e.Traversal), true
+	case *hclsyntax.RelativeTraversalExpr:
+		tr, ok := Address(e.Source)
+		if !ok {
+			return nil, false
+		}
+		return append(tr, attrSteps(e.Traversal)...), true
+	case *hclsyntax.IndexExpr:
+		return Address(e.Collection)
+	}
+	return nil, false
+}
+
+func attrSteps(tr hcl.Traversal) hcl.Traversal {
+	var out hcl.Traversal
+	for _, step := range tr {
+		switch step.(type) {
+		case hcl.TraverseRoot, hcl.TraverseAttr:
+			out = append(out, step)
+		}
+	}
+	return out
+}
+
+// Exists reports whether the address of a Target is declared in the module at dir, following
+// module calls into the child modules of the index. What lies in a child the index cannot
+// resolve counts as declared.
+func (ix *Index) Exists(dir, address string) bool {
+	m, ok := ix.Modules[dir]
+	if !ok {
+		return true
+	}
+	names := strings.Split(address, ".")
+	for len(names) >= 2 && names[0] == "module" {
+		call := names[1]
+		if _, ok := m.Declared["module."+call]; !ok {
+			return false
+		}
+		if names = names[2:]; len(names) == 0 {
+			return true
+		}
+		source, ok := m.Calls[call]
+		if !ok || ix.ResolveChild == nil {
+			return true
+		}
+		childDir, ok := ix.ResolveChild(m.Dir, call, source)
+		if !ok {
+			return true
+		}
+		if m, ok = ix.Modules[childDir]; !ok {
+			return true
+		}
+	}
+	if len(names) < 2 {
+		return false
+	}
+	addr := names[0] + "." + names[1]
+	if names[0] == "data" && len(names) > 2 {
+		addr += "." + names[2]
+	}
+	_, ok = m.Declared[addr]
+	return ok
+}
+
+func refFromTraversal(tr hcl.Traversal, scoped map[string]bool) (Ref, bool) {
+	names := AttrNames(tr)
+	if len(names) < 2 || scoped[names[0]] {
+		return Ref{}, false
+	}
+	switch names[0] {
+	case "var", "local":
+		return Ref{Address: names[0] + "." + names[1]}, true
+	case "module":
+		ref := Ref{Address: "module." + names[1]}
+		if len(names) > 2 {
+			ref.Output = names[2]
+		}
+		return ref, true
+	case "data":
+		if len(names) < 3 {
+			return Ref{}, false
+		}
+		return Ref{Address: "data." + names[1] + "." + names[2]}, true
+	case "each", "count", "path", "terraform", "self":
+		return Ref{}, false
+	}
+	// resource types are always <provider>_<type>
+	if !strings.Contains(names[0], "_") {
+		return Ref{}, false
+	}
+	return Ref{Address: names[0] + "." + names[1]}, true
+}
+
+// AttrNames returns the root and attribute names of a traversal up to the first index step.
+func AttrNames(tr hcl.Traversal) []string {
+	var names []string
+	for _, step := range tr {
+		switch v := step.(type) {
+		case hcl.TraverseRoot:
+			names = append(names, v.Name)
+		case hcl.TraverseAttr:
+			names = append(names, v.Name)
+		default:
+			return names
+		}
+	}
+	return names
+}
+
+// Dangling lists references, across all modules, to declarations that do not exist.
+// References to resource types never declared in a module are ignored: they usually come from
+// constructs the index does not model rather than from a broken rename.
+func (ix *Index) Dangling() []Ref {
+	var out []Ref
+	dirs := make([]string, 0, len(ix.Modules))
+	for dir := range ix.Modules {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		m := ix.Modules[dir]
+		types := m.resourceTypes()
+		for _, ref := range m.Refs {
+			decl, ok := m.Declared[ref.Address]
+			if !ok {
+				if isResourceAddress(ref.Address) && !types[strings.SplitN(ref.Address, ".", 2)[0]] {
+					continue
+				}
+				out = append(out, ref)
+				continue
+			}
+			if decl.Kind == "module" && ref.Output != "" && ix.ResolveChild != nil {
+				call := strings.TrimPrefix(ref.Address, "module.")
+				source, ok := m.Calls[call]
+				if !ok {
+					continue
+				}
+				childDir, ok := ix.ResolveChild(dir, call, source)
+				if !ok {
+					continue
+				}
+				child, ok := ix.Modules[childDir]
+				if !ok {
+					continue
+				}
+				if _, ok := child.Declared["output."+ref.Output]; !ok {
+					out = append(out, ref)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Referenced reports whether anything in the module points at addr.
+func (m *Module) Referenced(addr string) bool {
+	for _, ref := range m.Refs {
+		if ref.Address == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// Unused returns the declarations of the given kinds nothing references, sorted by position.
+// References from inside the declaration itself (a variable's validation) don't count, nor do
+// those from other unused declarations, so chains of dead locals are found at once.
+func (m *Module) Unused(kinds map[string]bool) []Decl {
+	dead := map[string]bool{}
+	for {
+		changed := false
+		for addr, d := range m.Declared {
+			if !kinds[d.Kind] || dead[addr] {
+				continue
+			}
+			used := false
+			for _, ref := range m.Refs {
+				if ref.Address == addr && !inside(ref, d) && !m.fromDead(ref, dead) {
+					used = true
+					break
+				}
+			}
+			if !used {
+				dead[addr] = true
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	var out []Decl
+	for addr := range dead {
+		out = append(out, m.Declared[addr])
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].File != out[j].File {
+			return out[i].File < out[j].File
+		}
+		return out[i].Range.Start.Byte < out[j].Range.Start.Byte
+	})
+	return out
+}
+
+func (m *Module) fromDead(ref Ref, dead map[string]bool) bool {
+	for addr := range dead {
+		if inside(ref, m.Declared[addr]) {
+			return true
+		}
+	}
+	return false
+}
+
+func inside(ref Ref, d Decl) bool {
+	return ref.File == d.File && ref.Range.Start.Byte >= d.Range.Start.Byte && ref.Range.End.Byte <= d.Range.End.Byte
+}
+
+func (m *Module) resourceTypes() map[string]bool {
+	types := map[string]bool{}
+	for _, d := range m.Declared {
+		if d.Kind == "resource" {
+			types[strings.SplitN(d.Address, ".", 2)[0]] = true
+		}
+	}
+	return types
+}
+
+func isResourceAddress(addr string) bool {
+	switch strings.SplitN(addr, ".", 2)[0] {
+	case "var", "local", "module", "data", "output":
+		return false
+	}
+	return true
+}
+
+// Target is the referenced address, including the output name for module outputs.
+func (r Ref) Target() string {
+	if r.Output != "" {
+		return r.Address + "." + r.Output
+	}
+	return r.Address
+}
+
+func (r Ref) String() string {
+	return fmt.Sprintf("%s:%d: %s", r.File, r.Line, r.Target())
+}
@@ -0,0 +1,181 @@
+package refs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIndexDeclarationsAndReferences(t *testing.T) {
+	ix := New()
+	src := `
+variable "region" {}
+
+locals {
+  names = [for n in var.list : upper(n)]
+}
+
+resource "aws_instance" "web" {
+  provider = aws.primary
+  ami      = var.region
+  tags     = { Name = local.names[0] }
+
+  dynamic "ebs" {
+    for_each = var.disks
+    content {
+      size = ebs.value
+    }
+  }
+
+  lifecycle {
+    ignore_changes = [tags.Name]
+  }
+}
+
+output "ip" {
+  value = aws_instance.web.private_ip
+}
+`
+	if err := ix.Add(filepath.Join("root", "main.tf"), []byte(src)); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	m := ix.Modules["root"]
+	for _, addr := range []string{"var.region", "local.names", "aws_instance.web", "output.ip"} {
+		if _, ok := m.Declared[addr]; !ok {
+			t.Fatalf("%s not declared: %v", addr, m.Declared)
+		}
+	}
+	got := map[string]bool{}
+	for _, ref := range m.Refs {
+		got[ref.Address] = true
+	}
+	for _, addr := range []string{"var.list", "var.region", "local.names", "var.disks", "aws_instance.web"} {
+		if !got[addr] {
+			t.Fatalf("missing reference %s in %v", addr, got)
+		}
+	}
+	for _, addr := range []string{"n.", "ebs.value", "aws.primary", "tags.Name"} {
+		if got[addr] {
+			t.Fatalf("unexpected reference %s", addr)
+		}
+	}
+
+	var dangling []string
+	for _, ref := range ix.Dangling() {
+		dangling = append(dangling, ref.Target())
+	}
+	if len(dangling) != 2 || dangling[0] != "var.list" || dangling[1] != "var.disks" {
+		t.Fatalf("unexpected dangling references: %v", dangling)
+	}
+}
+
+func TestDanglingModuleOutputs(t *testing.T) {
+	ix := New()
+	ix.ResolveChild = func(parentDir, _, source string) (string, bool) {
+		return filepath.Join(parentDir, source), true
+	}
+	_ = ix.Add(filepath.Join("root", "main.tf"), []byte(`
+module "net" { source = "child" }
+output "a" { value = module.net.vpc_id }
+output "b" { value = module.net.subnet_id }
+output "c" { value = module.gone.id }
+`))
+	_ = ix.Add(filepath.Join("root", "child", "outputs.tf"), []byte(`output "vpc_id" { value = "x" }`))
+
+	var dangling []string
+	for _, ref := range ix.Dangling() {
+		dangling = append(dangling, ref.Target())
+	}
+	if len(dangling) != 2 || dangling[0] != "module.net.subnet_id" || dangling[1] != "module.gone.id" {
+		t.Fatalf("unexpected dangling references: %v", dangling)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	ix := New()
+	ix.ResolveChild = func(parentDir, _, source string) (string, bool) {
+		return filepath.Join(parentDir, source), true
+	}
+	_ = ix.Add(filepath.Join("root", "main.tf"), []byte(`
+module "net" { source = "child" }
+moved {
+  from = module.net.aws_subnet.old
+  to   = module.net["a"].aws_subnet.this
+}
+import {
+  for_each = var.ids
+  to       = aws_vpc.main[each.key]
+  id       = each.value
+}
+`))
+	_ = ix.Add(filepath.Join("root", "child", "main.tf"), []byte(`resource "aws_subnet" "this" {}`))
+
+	m := ix.Modules["root"]
+	var got []string
+	for _, target := range m.Targets {
+		got = append(got, target.Block+" "+target.Address+" "+fmt.Sprint(ix.Exists("root", target.Address)))
+	}
+	want := "moved module.net.aws_subnet.this true, import aws_vpc.main false"
+	if strings.Join(got, ", ") != want {
+		t.Fatalf("unexpected targets: %v", got)
+	}
+	if !m.Referenced("var.ids") || m.Referenced("module.net.aws_subnet") {
+		t.Fatalf("unexpected references: %v", m.Refs)
+	}
+	for _, ref := range ix.Dangling() {
+		if ref.Address == "module.net" {
+			t.Fatalf("moved target reported as a missing module output: %v", ref)
+		}
+	}
+}
+
+func TestUnused(t *testing.T) {
+	ix := New()
+	src := `
+variable "region" {}
+
+variable "size" {
+  validation {
+    condition     = var.size > 0
+    error_message = "positive"
+  }
+}
+
+locals {
+  a    = local.b
+  b    = "x"
+  used = var.region
+}
+
+data "aws_ami" "ubuntu" {}
+
+data "aws_ami" "debian" {}
+
+module "net" {
+  source = "./net"
+  cidr   = "10.0.0.0/16"
+  count  = 1
+}
+
+output "ami" {
+  value = [data.aws_ami.debian.id, local.used]
+}
+`
+	if err := ix.Add(filepath.Join("root", "main.tf"), []byte(src)); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	m := ix.Modules["root"]
+	var got []string
+	for _, d := range m.Unused(map[string]bool{"variable": true, "local": true, "data": true}) {
+		got = append(got, d.Address)
+	}
+	// local.b is only used by the unused local.a; var.size only by its own validation
+	want := "var.size local.a local.b data.aws_ami.ubuntu"
+	if strings.Join(got, " ") != want {
+		t.Fatalf("Unused = %v, want %s", got, want)
+	}
+	if args := m.Args["net"]; len(args) != 1 || args[0] != "cidr" {
+		t.Fatalf("module args = %v", args)
+	}
+}
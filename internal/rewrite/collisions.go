@@ -0,0 +1,105 @@
+package rewrite
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+)
+
+// collisionTarget is an address inside one module directory; addresses already carry their namespace
+// (var., output., module., data.<type>. or <type>.).
+type collisionTarget struct {
+	dir  string
+	addr string
+}
+
+// resolveCollisions makes generated names unique per module according to strategy.
+// Decided renames keep their name; conflicts involving them are left for checkCollisions to report.
+func resolveCollisions(root string, renames []labelRename, declared map[string]map[string]string, strategy string) []labelRename {
+	if strategy == config.CollisionError {
+		return renames
+	}
+	renamedAway := map[collisionTarget]bool{}
+	for _, lr := range renames {
+		renamedAway[collisionTarget{filepath.Dir(lr.Path), lr.address()}] = true
+	}
+	occupied := map[collisionTarget]string{}
+	for dir, addrs := range declared {
+		for addr, path := range addrs {
+			t := collisionTarget{dir, addr}
+			if !renamedAway[t] {
+				occupied[t] = path
+			}
+		}
+	}
+	for _, lr := range renames {
+		if lr.Decided {
+			occupied[collisionTarget{filepath.Dir(lr.Path), blockAddress(lr.Kind, lr.Type, lr.New)}] = lr.Path
+		}
+	}
+
+	var out []labelRename
+	for _, lr := range renames {
+		if lr.Decided {
+			out = append(out, lr)
+			continue
+		}
+		dir := filepath.Dir(lr.Path)
+		t := collisionTarget{dir, blockAddress(lr.Kind, lr.Type, lr.New)}
+		if holder, taken := occupied[t]; taken {
+			if strategy == config.CollisionSkip {
+				fmt.Printf("skip %s (%s:%d): %s already declared in %s\n",
+					lr.address(), relPath(root, lr.Path), lr.Line, t.addr, relPath(root, holder))
+				// the label stays, so its current address remains taken
+				occupied[collisionTarget{dir, lr.address()}] = lr.Path
+				continue
+			}
+			base := lr.New
+			for suffix := 1; ; suffix++ {
+				lr.New = fmt.Sprintf("%s_%d", base, suffix)
+				t = collisionTarget{dir, blockAddress(lr.Kind, lr.Type, lr.New)}
+				if _, taken := occupied[t]; !taken {
+					break
+				}
+			}
+		}
+		occupied[t] = lr.Path
+		out = append(out, lr)
+	}
+	return out
+}
+
+// checkCollisions refuses renames that would produce the same address twice in one module.
+func checkCollisions(root string, renames []labelRename, declared map[string]map[string]string) error {
+	renamedAway := map[collisionTarget]bool{}
+	for _, lr := range renames {
+		renamedAway[collisionTarget{filepath.Dir(lr.Path), lr.address()}] = true
+	}
+
+	claims := map[collisionTarget][]string{}
+	for _, lr := range renames {
+		dir := filepath.Dir(lr.Path)
+		t := collisionTarget{dir, blockAddress(lr.Kind, lr.Type, lr.New)}
+		claims[t] = append(claims[t], lr.address())
+	}
+
+	var conflicts []string
+	for t, from := range claims {
+		if existing, ok := declared[t.dir][t.addr]; ok && !renamedAway[t] {
+			conflicts = append(conflicts, fmt.Sprintf("%s → %s already declared in %s", strings.Join(from, ", "), t.addr, relPath(root, existing)))
+			continue
+		}
+		if len(from) > 1 {
+			sort.Strings(from)
+			conflicts = append(conflicts, fmt.Sprintf("%s all become %s in %s", strings.Join(from, ", "), t.addr, relPath(root, t.dir)))
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	sort.Strings(conflicts)
+	return fmt.Errorf("rename collisions:\n  %s", strings.Join(conflicts, "\n  "))
+}
@@ -0,0 +1,360 @@
+package rewrite
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/engine"
+	"github.com/josdagaro/tfsuit/internal/modules"
+	"github.com/josdagaro/tfsuit/internal/refs"
+)
+
+// Graph is the tree as the provider resolver and rename propagation see it: module directories,
+// module calls, provider configurations and aliases and, optionally, resources.
+type Graph struct {
+	Nodes []*GraphNode `json:"nodes"`
+	Edges []GraphEdge  `json:"edges"`
+
+	byID map[string]*GraphNode
+}
+
+// GraphNode kinds: directory, module, provider (a configuration), alias (received from callers
+// or configuration_aliases), resource and data.
+type GraphNode struct {
+	ID         string   `json:"id"`
+	Kind       string   `json:"kind"`
+	Label      string   `json:"label"`
+	File       string   `json:"file,omitempty"` // relative to the graph root
+	Line       int      `json:"line,omitempty"`
+	Violations []string `json:"violations,omitempty"`
+}
+
+// GraphEdge kinds: declares, source (call → child directory), providers (parent → child
+// provider, per call), provider (resource → provider) and reference.
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Kind  string `json:"kind"`
+	Label string `json:"label,omitempty"`
+}
+
+// GraphOptions tunes BuildGraph.
+type GraphOptions struct {
+	Resources bool // add resource and data nodes
+}
+
+// BuildGraph indexes root like fix does and attaches the scan findings to the nodes they concern.
+func BuildGraph(root string, cfg *config.Config, opt GraphOptions) (*Graph, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	root = absRoot
+	files, err := collectTfFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	mods := modules.NewResolver(root, cfg.ModuleSources.Vendor(root))
+	files = mods.WithoutVendored(files)
+	resolver, err := buildProviderResolver(root, files, mods)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Graph{byID: map[string]*GraphNode{}}
+	rel := func(path string) string { return relPath(root, path) }
+	dirNode := func(dir string) string {
+		id := "dir:" + rel(dir)
+		g.node(id, "directory", rel(dir), "", 0)
+		return id
+	}
+	providerNode := func(dir, ref string) string {
+		scope := resolver.scopeForDir(dir)
+		id := "provider:" + rel(scope) + ":" + ref
+		if _, ok := g.byID[id]; !ok {
+			kind := "alias"
+			if a, ok := resolver.scope(scope).Aliases[ref]; ok && a.Defined {
+				kind = "provider"
+			}
+			g.node(id, kind, ref, "", 0)
+			g.edge(dirNode(scope), id, "declares", "")
+		}
+		return id
+	}
+
+	scopes := make([]string, 0, len(resolver.scopes))
+	for path := range resolver.scopes {
+		scopes = append(scopes, path)
+	}
+	sort.Strings(scopes)
+	for _, path := range scopes {
+		dirNode(path)
+		names := make([]string, 0, len(resolver.scopes[path].Aliases))
+		for name := range resolver.scopes[path].Aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			providerNode(path, name)
+		}
+	}
+
+	declNode := func(dir string, b *hclsyntax.Block, path string) string {
+		kind, addr := b.Type, addressOfBlock(b)
+		id := kind + ":" + rel(dir) + ":" + addr
+		g.node(id, kind, addr, rel(path), b.DefRange().Start.Line)
+		g.edge(dirNode(dir), id, "declares", "")
+		return id
+	}
+	for _, path := range files {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			continue
+		}
+		dir := filepath.Dir(path)
+		for _, b := range file.Body.(*hclsyntax.Body).Blocks {
+			if _, _, ok := blockLabels(b); !ok {
+				continue
+			}
+			switch b.Type {
+			case "module":
+				id := declNode(dir, b, path)
+				source, ok := moduleSourceString(b)
+				if !ok {
+					continue
+				}
+				child, ok := mods.Resolve(dir, b.Labels[0], source)
+				if !ok {
+					continue
+				}
+				g.edge(id, dirNode(child), "source", source)
+				if attr, ok := b.Body.Attributes["providers"]; ok {
+					if obj, ok := attr.Expr.(*hclsyntax.ObjectConsExpr); ok {
+						for _, item := range obj.Items {
+							key, val := objectKeyToString(item.KeyExpr), providerRefFromExpr(item.ValueExpr)
+							if key == "" || val == "" {
+								continue
+							}
+							g.edge(providerNode(dir, val), providerNode(child, key), "providers", "module."+b.Labels[0])
+						}
+					}
+				}
+			case "resource", "data":
+				if !opt.Resources {
+					continue
+				}
+				id := declNode(dir, b, path)
+				if attr, ok := b.Body.Attributes["provider"]; ok {
+					if ref := providerRefFromExpr(attr.Expr); ref != "" {
+						g.edge(id, providerNode(dir, ref), "provider", "")
+					}
+				}
+			}
+		}
+	}
+
+	// referencias entre llamadas a módulos (y recursos con --resources)
+	ix, err := refs.Build(files)
+	if err != nil {
+		return nil, err
+	}
+	for dir, m := range ix.Modules {
+		for _, ref := range m.Refs {
+			target, ok := m.Declared[ref.Address]
+			if !ok {
+				continue
+			}
+			from, ok := enclosingDecl(m, ref)
+			if !ok || from.Address == target.Address {
+				continue
+			}
+			fromID := from.Kind + ":" + rel(dir) + ":" + from.Address
+			toID := target.Kind + ":" + rel(dir) + ":" + target.Address
+			if g.byID[fromID] != nil && g.byID[toID] != nil {
+				g.edge(fromID, toID, "reference", "")
+			}
+		}
+	}
+
+	findings, _, err := engine.Scan(root, cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range findings {
+		file := rel(f.File)
+		attached := false
+		for _, n := range g.Nodes {
+			if n.File == file && n.Line == f.Line {
+				n.Violations = append(n.Violations, f.Message)
+				attached = true
+			}
+		}
+		if !attached {
+			n := g.byID[dirNode(filepath.Dir(f.File))]
+			n.Violations = append(n.Violations, fmt.Sprintf("%s:%d %s", filepath.Base(f.File), f.Line, f.Message))
+		}
+	}
+	g.sortEdges()
+	return g, nil
+}
+
+// enclosingDecl finds the module, resource or data block a reference sits in.
+func enclosingDecl(m *refs.Module, ref refs.Ref) (refs.Decl, bool) {
+	for _, d := range m.Declared {
+		switch d.Kind {
+		case "module", "resource", "data":
+		default:
+			continue
+		}
+		if d.File == ref.File && d.Range.Start.Byte <= ref.Range.Start.Byte && ref.Range.End.Byte <= d.Range.End.Byte {
+			return d, true
+		}
+	}
+	return refs.Decl{}, false
+}
+
+func (g *Graph) node(id, kind, label, file string, line int) {
+	if _, ok := g.byID[id]; ok {
+		return
+	}
+	n := &GraphNode{ID: id, Kind: kind, Label: label, File: file, Line: line}
+	g.byID[id] = n
+	g.Nodes = append(g.Nodes, n)
+}
+
+func (g *Graph) edge(from, to, kind, label string) {
+	e := GraphEdge{From: from, To: to, Kind: kind, Label: label}
+	for _, have := range g.Edges {
+		if have == e {
+			return
+		}
+	}
+	g.Edges = append(g.Edges, e)
+}
+
+func (g *Graph) sortEdges() {
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Kind < b.Kind
+	})
+}
+
+// Format renders the graph as dot (Graphviz), mermaid or json.
+func (g *Graph) Format(mode string) (string, error) {
+	switch mode {
+	case "json":
+		b, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(b) + "\n", nil
+	case "dot":
+		return g.dot(), nil
+	case "mermaid":
+		return g.mermaid(), nil
+	}
+	return "", fmt.Errorf("unknown graph format %q (valid: dot, mermaid, json)", mode)
+}
+
+var dotShapes = map[string]string{
+	"directory": "folder",
+	"module":    "component",
+	"provider":  "ellipse",
+	"alias":     "ellipse",
+	"resource":  "box",
+	"data":      "box",
+}
+
+func (g *Graph) dot() string {
+	var sb strings.Builder
+	sb.WriteString("digraph tfsuit {\n  rankdir=LR;\n  node [fontname=\"Helvetica\"];\n")
+	for _, n := range g.Nodes {
+		attrs := []string{fmt.Sprintf("label=%q", n.Label), "shape=" + dotShapes[n.Kind]}
+		if n.Kind == "alias" || n.Kind == "data" {
+			attrs = append(attrs, "style=dashed")
+		}
+		if len(n.Violations) > 0 {
+			attrs = append(attrs, "color=red", "fontcolor=red", fmt.Sprintf("tooltip=%q", strings.Join(n.Violations, "\n")))
+		}
+		fmt.Fprintf(&sb, "  %q [%s];\n", n.ID, strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		attrs := []string{}
+		if e.Label != "" {
+			attrs = append(attrs, fmt.Sprintf("label=%q", e.Label))
+		}
+		switch e.Kind {
+		case "declares":
+			attrs = append(attrs, "color=gray", "arrowhead=none")
+		case "reference":
+			attrs = append(attrs, "style=dashed")
+		case "providers", "provider":
+			attrs = append(attrs, "color=blue")
+		}
+		fmt.Fprintf(&sb, "  %q -> %q [%s];\n", e.From, e.To, strings.Join(attrs, ", "))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func (g *Graph) mermaid() string {
+	ids := make(map[string]string, len(g.Nodes))
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	var flagged []string
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.ID] = id
+		label := strings.ReplaceAll(n.Label, `"`, "#quot;")
+		switch n.Kind {
+		case "directory":
+			fmt.Fprintf(&sb, "  %s[/\"%s\"/]\n", id, label)
+		case "provider", "alias":
+			fmt.Fprintf(&sb, "  %s([\"%s\"])\n", id, label)
+		case "module":
+			fmt.Fprintf(&sb, "  %s[[\"%s\"]]\n", id, label)
+		default:
+			fmt.Fprintf(&sb, "  %s[\"%s\"]\n", id, label)
+		}
+		if len(n.Violations) > 0 {
+			flagged = append(flagged, id)
+		}
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		switch e.Kind {
+		case "declares":
+			arrow = "---"
+		case "reference":
+			arrow = "-.->"
+		}
+		if e.Label != "" {
+			fmt.Fprintf(&sb, "  %s %s|%s| %s\n", ids[e.From], arrow, strings.ReplaceAll(e.Label, "|", "/"), ids[e.To])
+		} else {
+			fmt.Fprintf(&sb, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+		}
+	}
+	if len(flagged) > 0 {
+		sb.WriteString("  classDef violation fill:#fdd,stroke:#c00,color:#900\n")
+		fmt.Fprintf(&sb, "  class %s violation\n", strings.Join(flagged, ","))
+	}
+	return sb.String()
+}
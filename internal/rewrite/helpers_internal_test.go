@@ -3,10 +3,15 @@ package rewrite
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	hcl "github.com/hashicorp/hcl/v2"
 	hclsyntax "github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/engine"
+	"github.com/josdagaro/tfsuit/internal/modules"
 )
 
 func parseBlock(t *testing.T, content string) *hclsyntax.Block {
@@ -94,9 +99,9 @@ func TestModuleSourceHelpers(t *testing.T) {
 	if !ok || source != "./child" {
 		t.Fatalf("moduleSourceString failed: %s %v", source, ok)
 	}
-	resolved, ok := resolveModuleSource(dir, dir, source)
+	resolved, ok := modules.NewResolver(dir, "").Resolve(dir, "mod", source)
 	if !ok || resolved != child {
-		t.Fatalf("resolveModuleSource failed: %s %v", resolved, ok)
+		t.Fatalf("Resolve failed: %s %v", resolved, ok)
 	}
 }
 
@@ -124,3 +129,80 @@ func TestProviderScopeAliasManagement(t *testing.T) {
 		t.Fatalf("buildProviderAliasName default wrong: %s", name)
 	}
 }
+
+func TestCommitStagedRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	staged := []stagedFile{{Path: path, Original: []byte("old"), Content: []byte("new")}}
+	missing := []fileRename{{Old: filepath.Join(dir, "missing.tf"), New: filepath.Join(dir, "other.tf")}}
+	if err := commitStaged(dir, staged, missing); err == nil {
+		t.Fatalf("expected failure for missing rename source")
+	}
+	out, _ := os.ReadFile(path)
+	if string(out) != "old" {
+		t.Fatalf("content not rolled back: %s", out)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Fatalf("file mode not preserved: %v", info.Mode())
+	}
+	if _, err := os.Stat(filepath.Join(dir, JournalFile)); !os.IsNotExist(err) {
+		t.Fatalf("journal should be removed after rollback")
+	}
+}
+
+func TestVerifyStagedRejectsInvalidHCL(t *testing.T) {
+	staged := []stagedFile{{Path: "main.tf", Content: []byte(`resource "a" "b" {`)}}
+	if err := verifyStaged(modules.NewResolver(".", ""), nil, staged); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
+
+func TestVerifyStagedReportsDanglingReferences(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.tf")
+	orig := "variable \"name\" {}\n\noutput \"name\" {\n  value = var.name\n}\n\noutput \"old\" {\n  value = var.missing\n}\n"
+	if err := os.WriteFile(path, []byte(orig), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	broken := strings.Replace(orig, `variable "name"`, `variable "renamed"`, 1)
+	staged := []stagedFile{{Path: path, Original: []byte(orig), Content: []byte(broken)}}
+	err := verifyStaged(modules.NewResolver(dir, ""), []string{path}, staged)
+	if err == nil {
+		t.Fatalf("expected dangling reference error")
+	}
+	if !strings.Contains(err.Error(), "main.tf:4: var.name") || strings.Contains(err.Error(), "var.missing") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRescanCountsCrossFileFindings(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "tfsuit.hcl")
+	if err := os.WriteFile(cfgPath, []byte(`
+variables { pattern = "^[a-z_]+$" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	// una violación por archivo y otra que solo ve el scan completo (moved sin destino)
+	if err := os.WriteFile(filepath.Join(dir, "main.tf"), []byte("variable \"Bad\" {}\n\nmoved {\n  from = aws_instance.old\n  to   = aws_instance.missing\n}\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	n, err := rescan(dir, cfg)
+	if err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	findings, _, _ := engine.Scan(dir, cfg)
+	if n != 2 || n != len(findings) {
+		t.Fatalf("rescan should count what scan reports: %d vs %d", n, len(findings))
+	}
+}
@@ -0,0 +1,322 @@
+package rewrite
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+)
+
+var (
+	labelIdentRe  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	providerRefRe = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)?$`)
+)
+
+type prompter struct {
+	r *bufio.Reader
+}
+
+func newPrompter(in io.Reader) *prompter {
+	if in == nil {
+		in = os.Stdin
+	}
+	return &prompter{r: bufio.NewReader(in)}
+}
+
+func (p *prompter) ask(question string) (string, error) {
+	fmt.Print(question)
+	line, err := p.r.ReadString('\n')
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		if line == "" {
+			fmt.Println()
+			return "", errors.New("interactive input closed before every change was reviewed")
+		}
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// decide asks until the answer is accept (proposed), skip (current) or a custom value validate accepts.
+func (p *prompter) decide(question, proposed, current string, validate func(string) error) (string, error) {
+	for {
+		line, err := p.ask(question)
+		if err != nil {
+			return "", err
+		}
+		switch strings.ToLower(line) {
+		case "", "y", "yes":
+			return proposed, nil
+		case "s", "skip", "n", "no":
+			return current, nil
+		}
+		if err := validate(line); err != nil {
+			fmt.Printf("  %v\n", err)
+			continue
+		}
+		return line, nil
+	}
+}
+
+// reviewer applies recorded decisions to the plan and, in interactive mode, asks about the rest.
+type reviewer struct {
+	root        string
+	cfgs        map[string]*config.Config // effective config per file
+	decisions   *config.RenameMap         // recorded in .tfsuit-renames.hcl
+	explicit    *config.RenameMap         // renames map in config and --rename-map, they win over recorded ones
+	interactive bool
+	prompt      *prompter
+	changed     bool
+}
+
+func (rv *reviewer) rel(path string) string {
+	rel, err := filepath.Rel(rv.root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// key prefixes addr with the module directory unless it is the fix root.
+func (rv *reviewer) key(path, addr string) string {
+	dir := rv.rel(filepath.Dir(path))
+	if dir == "." {
+		return addr
+	}
+	return dir + ":" + addr
+}
+
+func lookupDecision(m map[string]string, key, addr string) (string, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	v, ok := m[addr]
+	return v, ok
+}
+
+func (rv *reviewer) lookup(pick func(*config.RenameMap) map[string]string, key, addr string) (string, bool) {
+	for _, m := range []*config.RenameMap{rv.explicit, rv.decisions} {
+		if m == nil {
+			continue
+		}
+		if v, ok := lookupDecision(pick(m), key, addr); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// lookupRename returns the explicit or recorded new name for the label at addr declared in path.
+func (rv *reviewer) lookupRename(path, addr string) (string, bool) {
+	return rv.lookup(func(m *config.RenameMap) map[string]string { return m.Renames }, rv.key(path, addr), addr)
+}
+
+// mergeRenameMaps layers the --rename-map file over the renames map of the config.
+func mergeRenameMaps(fromConfig map[string]string, fromFlag *config.RenameMap) *config.RenameMap {
+	merged := &config.RenameMap{Renames: map[string]string{}, Files: map[string]string{}, Providers: map[string]string{}}
+	for k, v := range fromConfig {
+		merged.Renames[k] = v
+	}
+	if fromFlag != nil {
+		for k, v := range fromFlag.Renames {
+			merged.Renames[k] = v
+		}
+		for k, v := range fromFlag.Files {
+			merged.Files[k] = v
+		}
+		for k, v := range fromFlag.Providers {
+			merged.Providers[k] = v
+		}
+	}
+	return merged
+}
+
+// applyLabelDecisions takes names from the rename maps; decided renames are neither asked about nor suffixed.
+func (rv *reviewer) applyLabelDecisions(list []labelRename) ([]labelRename, error) {
+	var out []labelRename
+	for _, lr := range list {
+		addr := lr.address()
+		if v, ok := rv.lookupRename(lr.Path, addr); ok {
+			if !labelIdentRe.MatchString(v) {
+				return nil, fmt.Errorf("rename map: %q is not a valid name for %s", v, addr)
+			}
+			if v == lr.Old {
+				continue
+			}
+			lr.New = v
+			lr.Decided = true
+		}
+		out = append(out, lr)
+	}
+	return out, nil
+}
+
+func (rv *reviewer) promptLabels(list []labelRename) ([]labelRename, error) {
+	if !rv.interactive {
+		return list, nil
+	}
+	total := 0
+	for _, lr := range list {
+		if !lr.Decided {
+			total++
+		}
+	}
+	var out []labelRename
+	n := 0
+	for _, lr := range list {
+		if !lr.Decided {
+			n++
+			addr := lr.address()
+			q := fmt.Sprintf("[label %d/%d] %s (%s:%d) → %q  [Y]es/[s]kip/<custom name>: ",
+				n, total, addr, rv.rel(lr.Path), lr.Line, lr.New)
+			name, err := rv.prompt.decide(q, lr.New, lr.Old, labelValidator(ruleForKind(rv.cfgs[lr.Path], lr.Kind), lr.Type))
+			if err != nil {
+				return nil, err
+			}
+			lr.New = name
+			lr.Decided = true
+			rv.decisions.Renames[rv.key(lr.Path, addr)] = name
+			rv.changed = true
+		}
+		if lr.New != lr.Old {
+			out = append(out, lr)
+		}
+	}
+	return out, nil
+}
+
+func (rv *reviewer) reviewProviders(fixes map[string][]providerInsertion) (map[string][]providerInsertion, error) {
+	paths := make([]string, 0, len(fixes))
+	total := 0
+	for path, list := range fixes {
+		paths = append(paths, path)
+		total += len(list)
+	}
+	sort.Strings(paths)
+
+	out := map[string][]providerInsertion{}
+	n := 0
+	for _, path := range paths {
+		for _, ins := range fixes[path] {
+			n++
+			key := rv.key(path, ins.Address)
+			choice, ok := rv.lookup(func(m *config.RenameMap) map[string]string { return m.Providers }, key, ins.Address)
+			if !ok && rv.interactive {
+				q := fmt.Sprintf("[provider %d/%d] %s (%s:%d) ← %s  [Y]es/[s]kip/<custom provider>: ",
+					n, total, ins.Address, rv.rel(path), ins.Line, ins.describe())
+				var err error
+				choice, err = rv.prompt.decide(q, ins.describe(), "", validateProviderRef)
+				if err != nil {
+					return nil, err
+				}
+				rv.decisions.Providers[key] = choice
+				rv.changed = true
+				ok = true
+			}
+			if ok {
+				if choice == "" {
+					continue
+				}
+				if choice != ins.describe() {
+					ins.Aliases = nil
+					ins.Alias = choice
+				}
+			}
+			out[path] = append(out[path], ins)
+		}
+	}
+	return out, nil
+}
+
+func (rv *reviewer) reviewFiles(list []fileRename, files []string) ([]fileRename, error) {
+	taken := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		taken[f] = struct{}{}
+	}
+	for _, fr := range list {
+		delete(taken, fr.Old)
+		taken[fr.New] = struct{}{}
+	}
+
+	var out []fileRename
+	for i, fr := range list {
+		key := rv.rel(fr.Old)
+		oldBase := filepath.Base(fr.Old)
+		newBase := filepath.Base(fr.New)
+		choice, ok := rv.lookup(func(m *config.RenameMap) map[string]string { return m.Files }, key, key)
+		if !ok && rv.interactive {
+			q := fmt.Sprintf("[file %d/%d] %s → %s  [Y]es/[s]kip/<custom file name>: ", i+1, len(list), key, newBase)
+			validate := func(name string) error {
+				if err := fileNameValidator(rv.cfgs[fr.Old].Files)(name); err != nil {
+					return err
+				}
+				if _, busy := taken[filepath.Join(filepath.Dir(fr.Old), name)]; busy {
+					return fmt.Errorf("%q already exists", name)
+				}
+				return nil
+			}
+			var err error
+			choice, err = rv.prompt.decide(q, newBase, oldBase, validate)
+			if err != nil {
+				return nil, err
+			}
+			rv.decisions.Files[key] = choice
+			rv.changed = true
+			ok = true
+		}
+		if ok {
+			delete(taken, fr.New)
+			fr.New = filepath.Join(filepath.Dir(fr.Old), choice)
+			taken[fr.New] = struct{}{}
+		}
+		if fr.New != fr.Old {
+			out = append(out, fr)
+		}
+	}
+	return out, nil
+}
+
+func labelValidator(rule *config.Rule, typ string) func(string) error {
+	return func(name string) error {
+		if !labelIdentRe.MatchString(name) {
+			return fmt.Errorf("%q is not a valid Terraform identifier", name)
+		}
+		if rule == nil || rule.IsIgnored(name) {
+			return nil
+		}
+		if !rule.Matches(name) {
+			return errors.New(rule.Explain("name", name))
+		}
+		if v := rule.Violations("name", typ, name); len(v) > 0 {
+			return errors.New(v[0])
+		}
+		return nil
+	}
+}
+
+func fileNameValidator(rule *config.Rule) func(string) error {
+	return func(name string) error {
+		if name != filepath.Base(name) || !strings.HasSuffix(strings.ToLower(name), ".tf") {
+			return fmt.Errorf("%q must be a plain .tf file name", name)
+		}
+		if rule != nil && !rule.IsIgnored(name) && !rule.Matches(name) {
+			return fmt.Errorf("%q does not match pattern %s", name, rule.Pattern)
+		}
+		return nil
+	}
+}
+
+func validateProviderRef(ref string) error {
+	if !providerRefRe.MatchString(ref) {
+		return fmt.Errorf("%q is not a provider reference like aws.primary", ref)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package rewrite
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/cache"
+	"github.com/josdagaro/tfsuit/internal/modules"
+)
+
+// JournalFile records the last `fix --write` so it can be undone. It lives under the fix root's
+// .terraform directory, which Terraform projects already keep out of version control.
+var JournalFile = filepath.Join(".terraform", "tfsuit-journal.json")
+
+type stagedFile struct {
+	Path     string
+	Original []byte
+	Content  []byte
+	Created  bool // the file does not exist yet
+}
+
+type journal struct {
+	WrittenAt string          `json:"written_at"`
+	Files     []journalFile   `json:"files"`
+	Renames   []journalRename `json:"renames,omitempty"`
+}
+
+type journalFile struct {
+	Path     string      `json:"path"` // relative to the fix root, before renames
+	Original string      `json:"original"`
+	Hash     string      `json:"hash"` // of the written content, to detect later edits
+	Mode     os.FileMode `json:"mode"`
+	Created  bool        `json:"created,omitempty"` // undo removes it instead of restoring it
+}
+
+type journalRename struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// verifyStaged makes sure every staged file still parses and no reference is left dangling.
+func verifyStaged(mods *modules.Resolver, files []string, staged []stagedFile) error {
+	for _, sf := range staged {
+		if _, diags := hclsyntax.ParseConfig(sf.Content, sf.Path, hcl.Pos{Line: 1, Column: 1}); diags.HasErrors() {
+			return fmt.Errorf("fix aborted, %s would not parse: %s", sf.Path, diags.Error())
+		}
+	}
+	return checkReferences(mods, files, staged)
+}
+
+// commitStaged writes the journal, then every staged file through a temp file + rename, then the
+// file renames. Any failure rolls back what was already committed.
+func commitStaged(root string, staged []stagedFile, renames []fileRename) error {
+	for _, fr := range renames {
+		if _, err := os.Stat(fr.New); err == nil {
+			return fmt.Errorf("fix aborted, %s already exists", fr.New)
+		}
+	}
+
+	j := journal{WrittenAt: time.Now().UTC().Format(time.RFC3339)}
+	modes := map[string]os.FileMode{}
+	for _, sf := range staged {
+		mode := os.FileMode(0o644)
+		if info, err := os.Stat(sf.Path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		modes[sf.Path] = mode
+		j.Files = append(j.Files, journalFile{
+			Path:     relPath(root, sf.Path),
+			Original: string(sf.Original),
+			Hash:     cache.Hash(sf.Content),
+			Mode:     mode,
+			Created:  sf.Created,
+		})
+	}
+	for _, fr := range renames {
+		j.Renames = append(j.Renames, journalRename{Old: relPath(root, fr.Old), New: relPath(root, fr.New)})
+	}
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	journalPath := filepath.Join(root, JournalFile)
+	if err := os.MkdirAll(filepath.Dir(journalPath), 0o755); err != nil {
+		return err
+	}
+	if err := atomicWrite(journalPath, data, 0o644); err != nil {
+		return err
+	}
+
+	var written []stagedFile
+	var moved []fileRename
+	rollback := func(cause error) error {
+		for i := len(moved) - 1; i >= 0; i-- {
+			_ = os.Rename(moved[i].New, moved[i].Old)
+		}
+		for _, sf := range written {
+			if sf.Created {
+				_ = os.Remove(sf.Path)
+				continue
+			}
+			_ = atomicWrite(sf.Path, sf.Original, modes[sf.Path])
+		}
+		_ = os.Remove(journalPath)
+		_ = os.Remove(filepath.Dir(journalPath)) // .terraform, si quedó vacío
+		return fmt.Errorf("fix rolled back: %w", cause)
+	}
+
+	for _, sf := range staged {
+		if err := atomicWrite(sf.Path, sf.Content, modes[sf.Path]); err != nil {
+			return rollback(err)
+		}
+		written = append(written, sf)
+	}
+	for _, fr := range renames {
+		if err := os.Rename(fr.Old, fr.New); err != nil {
+			return rollback(err)
+		}
+		moved = append(moved, fr)
+	}
+	return nil
+}
+
+// atomicWrite replaces path with data via a temp file in the same directory.
+func atomicWrite(path string, data []byte, mode os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tfsuit-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, mode); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// Undo reverts the last `fix --write` recorded in root's journal. Files edited since then are left
+// alone and reported, so nothing newer is overwritten.
+func Undo(root string) error {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	root = absRoot
+	journalPath := filepath.Join(root, JournalFile)
+	data, err := ioutil.ReadFile(journalPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("nothing to undo: %s not found", journalPath)
+		}
+		return err
+	}
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return fmt.Errorf("corrupted journal %s: %w", journalPath, err)
+	}
+
+	// where each journaled file lives now
+	current := map[string]string{}
+	for _, fr := range j.Renames {
+		current[fr.Old] = fr.New
+	}
+	var conflicts []string
+	for _, jf := range j.Files {
+		rel := jf.Path
+		if moved, ok := current[rel]; ok {
+			rel = moved
+		}
+		content, err := ioutil.ReadFile(filepath.Join(root, rel))
+		if err != nil {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %v", rel, err))
+			continue
+		}
+		if cache.Hash(content) != jf.Hash {
+			conflicts = append(conflicts, fmt.Sprintf("%s changed after the fix", rel))
+		}
+	}
+	for _, fr := range j.Renames {
+		if _, err := os.Stat(filepath.Join(root, fr.Old)); err == nil {
+			conflicts = append(conflicts, fmt.Sprintf("%s exists again", fr.Old))
+		}
+	}
+	if len(conflicts) > 0 {
+		msg := "cannot undo safely:"
+		for _, c := range conflicts {
+			msg += "\n  " + c
+		}
+		return errors.New(msg)
+	}
+
+	for i := len(j.Renames) - 1; i >= 0; i-- {
+		fr := j.Renames[i]
+		if err := os.Rename(filepath.Join(root, fr.New), filepath.Join(root, fr.Old)); err != nil {
+			return err
+		}
+		fmt.Printf("renamed %s -> %s\n", fr.New, fr.Old)
+	}
+	for _, jf := range j.Files {
+		if jf.Created {
+			if err := os.Remove(filepath.Join(root, jf.Path)); err != nil {
+				return err
+			}
+			fmt.Printf("removed %s\n", jf.Path)
+			continue
+		}
+		if err := atomicWrite(filepath.Join(root, jf.Path), []byte(jf.Original), jf.Mode); err != nil {
+			return err
+		}
+		fmt.Printf("restored %s\n", jf.Path)
+	}
+	if err := os.Remove(journalPath); err != nil {
+		return err
+	}
+	_ = os.Remove(filepath.Dir(journalPath)) // .terraform, si quedó vacío
+	fmt.Printf("\nSummary: undid fix from %s; restored %d files; reverted %d file renames.\n",
+		j.WrittenAt, len(j.Files), len(j.Renames))
+	return nil
+}
@@ -0,0 +1,130 @@
+package rewrite
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/parser"
+)
+
+// layoutMove is a block fix takes out of a file the layout does not allow it in.
+type layoutMove struct {
+	Target string // file of the same directory it goes to
+	Text   string // the block, with the comments right above it
+}
+
+// extractMisplaced removes from src the blocks the layout puts in another file. Kinds whose
+// allowed names are all globs are left in place (scan still reports them).
+func extractMisplaced(path string, src []byte, layout *config.Layout) ([]byte, []layoutMove) {
+	if layout == nil {
+		return src, nil
+	}
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return src, nil
+	}
+	name := filepath.Base(path)
+	var moves []layoutMove
+	var ranges []hcl.Range
+	comments := parser.CommentLines(src)
+	for _, b := range file.Body.(*hclsyntax.Body).Blocks {
+		target := layout.Target(b.Type)
+		if target == "" || layout.Allows(b.Type, name) {
+			continue
+		}
+		rng := b.Range()
+		end := rng.End.Byte
+		for end < len(src) && src[end] != '\n' {
+			end++
+		}
+		text := string(src[leadingComments(src, comments, rng.Start.Byte):end]) + "\n"
+		moves = append(moves, layoutMove{Target: filepath.Join(filepath.Dir(path), target), Text: text})
+		ranges = append(ranges, rng)
+	}
+	if len(moves) == 0 {
+		return src, nil
+	}
+	return applyEdits(src, deletions(src, ranges)), moves
+}
+
+// appendBlocks adds moved blocks at the end of content, one blank line apart.
+func appendBlocks(content []byte, texts []string) []byte {
+	out := bytes.TrimRight(content, " \t\r\n")
+	out = append([]byte{}, out...)
+	for _, text := range texts {
+		if len(out) > 0 {
+			out = append(out, "\n\n"...)
+		}
+		out = append(out, bytes.TrimRight([]byte(text), "\n")...)
+	}
+	return append(out, '\n')
+}
+
+// stageLayoutTargets appends the moved blocks to their target files: to the staged content when the
+// file already changes, to the file on disk otherwise, or to a new file. A target that a pending
+// file rename produces receives them under its current name.
+func stageLayoutTargets(staged []stagedFile, targets map[string][]string, renames []fileRename, tree *config.Tree, opt Options) ([]stagedFile, error) {
+	renamedFrom := map[string]string{}
+	for _, fr := range renames {
+		renamedFrom[fr.New] = fr.Old
+	}
+	paths := make([]string, 0, len(targets))
+	for target := range targets {
+		paths = append(paths, target)
+	}
+	sort.Strings(paths)
+
+	for _, target := range paths {
+		path := target
+		if old, ok := renamedFrom[target]; ok {
+			path = old
+		}
+		idx := -1
+		for i := range staged {
+			if staged[i].Path == path {
+				idx = i
+				break
+			}
+		}
+		var sf stagedFile
+		if idx >= 0 {
+			sf = staged[idx]
+		} else {
+			sf = stagedFile{Path: path}
+			data, err := ioutil.ReadFile(path)
+			switch {
+			case err == nil:
+				sf.Original = data
+			case os.IsNotExist(err):
+				sf.Created = true
+			default:
+				return nil, err
+			}
+			sf.Content = sf.Original
+		}
+
+		content := appendBlocks(sf.Content, targets[target])
+		cfg, err := tree.For(filepath.Dir(path))
+		if err != nil {
+			return nil, err
+		}
+		if opt.allows("ordering") {
+			content, _ = orderContent(path, content, cfg.Ordering)
+		}
+		content, _ = enforceBlockSpacing(path, content, cfg.Spacing, opt)
+		sf.Content = content
+		if idx >= 0 {
+			staged[idx] = sf
+		} else {
+			staged = append(staged, sf)
+		}
+	}
+	return staged, nil
+}
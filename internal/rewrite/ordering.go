@@ -0,0 +1,134 @@
+package rewrite
+
+import (
+	"sort"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/parser"
+)
+
+// lineSpan is a run of whole lines (1-based, inclusive) that moves as a unit.
+type lineSpan struct {
+	Start int
+	End   int
+}
+
+// orderContent applies ordering to content: first the meta-arguments inside resource, data and
+// module blocks, then the alphabetical order of the sorted kinds. Comments right above a block or
+// attribute move with it; blank lines between them stay where they are. It returns how many block
+// bodies and kinds were rearranged.
+func orderContent(path string, content []byte, ordering *config.Ordering) ([]byte, int) {
+	if ordering == nil {
+		return content, 0
+	}
+	changes := 0
+	body, ok := parseBody(path, content)
+	if !ok {
+		return content, 0
+	}
+	if ordering.MetaArguments != "" {
+		// el reordenamiento no cambia las líneas de los bloques que siguen
+		lines, comments := splitLines(content), parser.CommentLines(content)
+		for _, b := range body.Blocks {
+			switch b.Type {
+			case "resource", "data", "module":
+			default:
+				continue
+			}
+			if parser.Arranged(b, ordering) {
+				continue
+			}
+			items := parser.BodyItems(b.Body)
+			names := make([]string, len(items))
+			spans := make([]lineSpan, len(items))
+			floor := b.OpenBraceRange.Start.Line
+			fits := true
+			for i, it := range items {
+				names[i] = it.Name
+				start := parser.CommentStart(comments, it.Range.Start.Line, floor)
+				if start <= floor || it.Range.End.Line >= b.CloseBraceRange.Start.Line {
+					fits = false // comparte línea con otro elemento o con las llaves
+					break
+				}
+				spans[i] = lineSpan{Start: start, End: it.Range.End.Line}
+				floor = it.Range.End.Line
+			}
+			if !fits {
+				continue
+			}
+			// el reordenamiento conserva las líneas del bloque: los bloques siguientes no se mueven
+			lines = reorderSpans(lines, spans, ordering.Arrange(b.Type, names))
+			changes++
+		}
+		content = joinLines(lines)
+	}
+
+	for _, kind := range config.Kinds {
+		if !ordering.Sorts(kind) {
+			continue
+		}
+		body, ok := parseBody(path, content)
+		if !ok {
+			break
+		}
+		lines, comments := splitLines(content), parser.CommentLines(content)
+		var spans []lineSpan
+		var keys []string
+		floor := 0
+		fits := true
+		for _, info := range topLevelBlockInfos(body) {
+			if info.Kind == kind {
+				start := parser.CommentStart(comments, info.StartLine, floor)
+				if start <= floor {
+					fits = false
+					break
+				}
+				spans = append(spans, lineSpan{Start: start, End: info.EndLine})
+				keys = append(keys, info.Key)
+			}
+			floor = info.EndLine
+		}
+		if !fits || sort.StringsAreSorted(keys) {
+			continue
+		}
+		order := make([]int, len(keys))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool { return keys[order[i]] < keys[order[j]] })
+		content = joinLines(reorderSpans(lines, spans, order))
+		changes++
+	}
+	return content, changes
+}
+
+func parseBody(path string, content []byte) (*hclsyntax.Body, bool) {
+	file, diags := hclsyntax.ParseConfig(content, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, false
+	}
+	return file.Body.(*hclsyntax.Body), true
+}
+
+// reorderSpans puts the lines of spans[order[i]] in place of spans[i]; what lies between the
+// spans stays where it is.
+func reorderSpans(lines []string, spans []lineSpan, order []int) []string {
+	out := make([]string, 0, len(lines))
+	prev := 1
+	for i, sp := range spans {
+		out = append(out, lines[prev-1:sp.Start-1]...)
+		src := spans[order[i]]
+		out = append(out, lines[src.Start-1:src.End]...)
+		prev = sp.End + 1
+	}
+	return append(out, lines[prev-1:]...)
+}
+
+// joinLines is the inverse of splitLines.
+func joinLines(lines []string) []byte {
+	return []byte(strings.Join(lines, "\n"))
+}
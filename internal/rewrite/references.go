@@ -0,0 +1,248 @@
+package rewrite
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/refs"
+)
+
+// textEdit replaces src[Start:End] with Text; Start == End is an insertion.
+type textEdit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+func applyEdits(src []byte, edits []textEdit) []byte {
+	if len(edits) == 0 {
+		return src
+	}
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Start > edits[j].Start })
+	out := src
+	for _, e := range edits {
+		buf := make([]byte, 0, len(out)+len(e.Text))
+		buf = append(buf, out[:e.Start]...)
+		buf = append(buf, e.Text...)
+		buf = append(buf, out[e.End:]...)
+		out = buf
+	}
+	return out
+}
+
+// renameIndex answers "is this declaration or reference affected by a rename?" per module directory.
+type renameIndex struct {
+	byDir       map[string]map[string]labelRename // dir → old address → rename
+	moduleCalls map[string]map[string]string      // parent dir → call name → child dir
+}
+
+func newRenameIndex(renames []labelRename, moduleCalls map[string]map[string]string) *renameIndex {
+	ix := &renameIndex{byDir: map[string]map[string]labelRename{}, moduleCalls: moduleCalls}
+	for _, lr := range renames {
+		dir := filepath.Dir(lr.Path)
+		if ix.byDir[dir] == nil {
+			ix.byDir[dir] = map[string]labelRename{}
+		}
+		ix.byDir[dir][lr.address()] = lr
+	}
+	return ix
+}
+
+func (ix *renameIndex) lookup(dir, addr string) (labelRename, bool) {
+	lr, ok := ix.byDir[dir][addr]
+	return lr, ok
+}
+
+// labelEdits rewrites the name label of every renamed declaration in body.
+func (ix *renameIndex) labelEdits(path string, src []byte, body *hclsyntax.Body) []textEdit {
+	dir := filepath.Dir(path)
+	var edits []textEdit
+	for _, b := range body.Blocks {
+		typ, name, ok := blockLabels(b)
+		if !ok {
+			continue
+		}
+		lr, ok := ix.lookup(dir, blockAddress(b.Type, typ, name))
+		if !ok {
+			continue
+		}
+		rng := b.LabelRanges[len(b.LabelRanges)-1]
+		if b.Type != "resource" && b.Type != "data" {
+			rng = b.LabelRanges[0]
+		}
+		start, end := rng.Start.Byte, rng.End.Byte
+		if end-start >= 2 && src[start] == '"' {
+			start++
+			end--
+		}
+		edits = append(edits, textEdit{Start: start, End: end, Text: lr.New})
+	}
+	return edits
+}
+
+// argumentEdits renames the arguments of module calls whose child variable is renamed.
+func (ix *renameIndex) argumentEdits(path string, body *hclsyntax.Body) []textEdit {
+	dir := filepath.Dir(path)
+	var edits []textEdit
+	for _, b := range body.Blocks {
+		if b.Type != "module" || len(b.Labels) == 0 {
+			continue
+		}
+		child, ok := ix.moduleCalls[dir][b.Labels[0]]
+		if !ok {
+			continue
+		}
+		for name, attr := range b.Body.Attributes {
+			if lr, ok := ix.lookup(child, "var."+name); ok {
+				rng := attr.NameRange
+				edits = append(edits, textEdit{Start: rng.Start.Byte, End: rng.End.Byte, Text: lr.New})
+			}
+		}
+	}
+	return edits
+}
+
+// referenceEdits rewrites traversals (var.x, module.x.out, data.t.x, t.x) that point at renamed
+// labels, and the from/to addresses of moved, import and removed blocks.
+func (ix *renameIndex) referenceEdits(path string, src []byte, body *hclsyntax.Body) []textEdit {
+	dir := filepath.Dir(path)
+	var edits []textEdit
+	addresses := map[hclsyntax.Node]bool{}
+	for _, b := range body.Blocks {
+		switch b.Type {
+		case "moved", "import", "removed":
+		default:
+			continue
+		}
+		for _, name := range []string{"from", "to"} {
+			attr, ok := b.Body.Attributes[name]
+			if !ok {
+				continue
+			}
+			if tr, ok := refs.Address(attr.Expr); ok {
+				edits = append(edits, ix.addressEdits(dir, src, tr)...)
+			}
+			hclsyntax.VisitAll(attr.Expr, func(n hclsyntax.Node) hcl.Diagnostics {
+				addresses[n] = true
+				return nil
+			})
+		}
+	}
+	hclsyntax.VisitAll(body, func(n hclsyntax.Node) hcl.Diagnostics {
+		expr, ok := n.(*hclsyntax.ScopeTraversalExpr)
+		if !ok || addresses[n] {
+			return nil
+		}
+		edits = append(edits, ix.traversalEdits(dir, src, expr.Traversal)...)
+		return nil
+	})
+	return edits
+}
+
+func (ix *renameIndex) traversalEdits(dir string, src []byte, tr hcl.Traversal) []textEdit {
+	names := refs.AttrNames(tr)
+	if len(names) < 2 {
+		return nil
+	}
+	var edits []textEdit
+	rename := func(step int, addr string) {
+		lr, ok := ix.lookup(dir, addr)
+		if !ok {
+			return
+		}
+		if e, ok := attrEdit(src, tr[step], lr.Old, lr.New); ok {
+			edits = append(edits, e)
+		}
+	}
+
+	switch names[0] {
+	case "var":
+		rename(1, "var."+names[1])
+	case "module":
+		rename(1, "module."+names[1])
+		if len(names) >= 3 {
+			if child, ok := ix.moduleCalls[dir][names[1]]; ok {
+				if lr, ok := ix.lookup(child, "output."+names[2]); ok {
+					if e, ok := attrEdit(src, tr[2], lr.Old, lr.New); ok {
+						edits = append(edits, e)
+					}
+				}
+			}
+		}
+	case "data":
+		if len(names) >= 3 {
+			rename(2, "data."+names[1]+"."+names[2])
+		}
+	case "local", "each", "count", "path", "terraform", "self":
+	default:
+		rename(1, names[0]+"."+names[1])
+	}
+	return edits
+}
+
+// addressEdits rewrites an address from refs.Address, following module.<call> steps into the
+// child module: module.net.aws_subnet.a follows a rename of aws_subnet.a inside net.
+func (ix *renameIndex) addressEdits(dir string, src []byte, tr hcl.Traversal) []textEdit {
+	names := refs.AttrNames(tr)
+	var edits []textEdit
+	rename := func(step int, addr string) {
+		lr, ok := ix.lookup(dir, addr)
+		if !ok {
+			return
+		}
+		if e, ok := attrEdit(src, tr[step], lr.Old, lr.New); ok {
+			edits = append(edits, e)
+		}
+	}
+	i := 0
+	for len(names)-i >= 2 && names[i] == "module" {
+		rename(i+1, "module."+names[i+1])
+		child, ok := ix.moduleCalls[dir][names[i+1]]
+		if !ok {
+			return edits
+		}
+		dir = child
+		i += 2
+	}
+	switch {
+	case len(names)-i < 2:
+	case names[i] == "data":
+		if len(names)-i >= 3 {
+			rename(i+2, "data."+names[i+1]+"."+names[i+2])
+		}
+	default:
+		rename(i+1, names[i]+"."+names[i+1])
+	}
+	return edits
+}
+
+func attrEdit(src []byte, step hcl.Traverser, old, newName string) (textEdit, bool) {
+	attr, ok := step.(hcl.TraverseAttr)
+	if !ok {
+		return textEdit{}, false
+	}
+	end := attr.SrcRange.End.Byte
+	start := end - len(old)
+	if start < 0 || end > len(src) || string(src[start:end]) != old {
+		return textEdit{}, false
+	}
+	return textEdit{Start: start, End: end, Text: newName}, true
+}
+
+func relPath(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// movedBlock renders the moved {} block that keeps state attached after a resource or module rename.
+func movedBlock(lr labelRename) string {
+	to := blockAddress(lr.Kind, lr.Type, lr.New)
+	return fmt.Sprintf("\nmoved {\n  from = %s\n  to   = %s\n}\n", lr.address(), to)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -17,7 +18,7 @@ import (
 	cty "github.com/zclconf/go-cty/cty"
 
 	"github.com/josdagaro/tfsuit/internal/config"
-	"github.com/josdagaro/tfsuit/internal/model"
+	"github.com/josdagaro/tfsuit/internal/modules"
 	"github.com/josdagaro/tfsuit/internal/parser"
 )
 
@@ -26,9 +27,14 @@ import (
 /* -------------------------------------------------------------------------- */
 
 type Options struct {
-	Write    bool
-	DryRun   bool
-	FixKinds map[string]bool
+	Write        bool
+	DryRun       bool
+	FixKinds     map[string]bool
+	Interactive  bool              // ask before every planned change
+	Input        io.Reader         // answers for Interactive (defaults to os.Stdin)
+	RenameMap    *config.RenameMap // explicit renames (--rename-map), applied even to conforming labels
+	Moved        bool              // emit moved {} blocks for renamed resources and modules
+	RemoveUnused bool              // delete the declarations reported by the unused checks
 }
 
 func (opt Options) allows(kind string) bool {
@@ -45,11 +51,46 @@ func toSnake(s string) string {
 	return regexp.MustCompile(`_+`).ReplaceAllString(s, "_")
 }
 
-type rename struct{ Old, New string }
+// labelRename is a planned change of a block label (the declaration itself).
+type labelRename struct {
+	Path string
+	Line int
+	Kind string
+	Type string // resource/data type, empty for other kinds
+	Old  string
+	New  string
+
+	Decided bool // New comes from a rename map or an interactive answer, not from toSnake
+}
+
+func (lr labelRename) address() string {
+	return blockAddress(lr.Kind, lr.Type, lr.Old)
+}
 
 type providerInsertion struct {
 	Offset  int
-	Payload string
+	Path    string
+	Line    int
+	Address string
+	Kind    string
+	Indent  string
+	Alias   string   // provider reference to inject
+	Aliases []string // passthrough mappings for modules with configuration_aliases
+}
+
+func (p providerInsertion) payload() string {
+	if len(p.Aliases) > 0 {
+		return renderModuleProvidersPayload(p.Indent, p.Aliases)
+	}
+	return renderProviderPayload(p.Kind, p.Indent, providerSelection{Alias: p.Alias, Type: providerTypeFromAlias(p.Alias)})
+}
+
+// describe returns the mapping that will be injected, for prompts and logs.
+func (p providerInsertion) describe() string {
+	if len(p.Aliases) > 0 {
+		return strings.Join(p.Aliases, ", ")
+	}
+	return p.Alias
 }
 
 type providerSelection struct {
@@ -63,15 +104,16 @@ type fileRename struct {
 }
 
 type blockInfo struct {
-	Kind       string
-	StartLine  int
-	EndLine    int
-	SingleLine bool
+	Kind      string
+	Key       string // label, TYPE.NAME for resources and data sources (ordering.sort)
+	StartLine int
+	EndLine   int
 }
 
 var (
 	errNoProviderDefinitions = errors.New("no provider configurations defined")
 	errNoProviderInScope     = errors.New("no provider available in scope")
+	errAmbiguousProvider     = errors.New("ambiguous provider")
 )
 
 /* -------------------------------------------------------------------------- */
@@ -89,19 +131,42 @@ func Run(root string, cfg *config.Config, opt Options) error {
 	if err != nil {
 		return err
 	}
+	mods := modules.NewResolver(root, cfg.ModuleSources.Vendor(root))
+	files = mods.WithoutVendored(files)
 
-	resolver, err := buildProviderResolver(root, files)
+	// config efectiva por archivo (tfsuit.hcl anidados)
+	tree := config.NewTree(root, cfg)
+	cfgs := make(map[string]*config.Config, len(files))
+	for _, path := range files {
+		if cfgs[path], err = tree.For(filepath.Dir(path)); err != nil {
+			return err
+		}
+	}
+
+	resolver, err := buildProviderResolver(root, files, mods)
 	if err != nil {
 		return err
 	}
 
-	fileRen := map[string][]rename{}
-	globalRen := map[string]string{} // old → new
+	decisions, err := config.LoadRenameMap(filepath.Join(root, config.RenamesFile))
+	if err != nil {
+		return err
+	}
+	explicit := mergeRenameMaps(cfg.Renames, opt.RenameMap)
+	rev := &reviewer{root: root, cfgs: cfgs, decisions: decisions, explicit: explicit, interactive: opt.Interactive}
+	if opt.Interactive {
+		rev.prompt = newPrompter(opt.Input)
+	}
+
+	var labelRenames []labelRename
 	providerFixes := map[string][]providerInsertion{}
-	blockInfosByPath := map[string][]blockInfo{}
+	var ambiguous []string
+	sensitiveFixes := map[string][]textEdit{}
+	declared := map[string]map[string]string{}    // dir → address → declaring file
+	moduleCalls := map[string]map[string]string{} // parent dir → call name → child dir
 	var pendingFileRenames []fileRename
-	if cfg.Files != nil && opt.allows("file") {
-		pendingFileRenames = planFileRenames(files, cfg.Files)
+	if opt.allows("file") {
+		pendingFileRenames = planFileRenames(files, cfgs)
 	}
 	// métricas para el resumen final
 	var (
@@ -111,17 +176,23 @@ func Run(root string, cfg *config.Config, opt Options) error {
 		xrefHits            int // cantidad de referencias cruzadas reemplazadas
 		providerAssignments int // cantidad de providers inyectados
 		fileRenameCount     int // cantidad de archivos renombrados
+		movedBlocks         int // cantidad de bloques moved agregados
+		tagsAdded           int // cantidad de tags requeridos insertados
+		sensitiveMarked     int // cantidad de variables/outputs marcados sensitive
+		layoutMoved         int // cantidad de bloques movidos a su archivo (layout)
+		reordered           int // cantidad de cuerpos y grupos de bloques reordenados
 	)
-	hasProviderFixes := false
-	hasFileRenames := len(pendingFileRenames) > 0
-	spacingEnabled := cfg.Spacing != nil && cfg.Spacing.EnabledValue()
-
-	requireProvider := map[string]bool{
-		"module":   cfg.Modules.RequiresProvider(),
-		"resource": cfg.Resources.RequiresProvider(),
-	}
-	if cfg.Data != nil {
-		requireProvider["data"] = cfg.Data.RequiresProvider()
+	spacingEnabled, layoutEnabled, orderingEnabled := false, false, false
+	for _, fc := range cfgs {
+		if fc.Spacing != nil && fc.Spacing.EnabledValue() {
+			spacingEnabled = true
+		}
+		if fc.Layout != nil && opt.allows("layout") {
+			layoutEnabled = true
+		}
+		if fc.Ordering != nil && opt.allows("ordering") {
+			orderingEnabled = true
+		}
 	}
 
 	/* ---------- 1️⃣  primera pasada: detectar violaciones ---------------- */
@@ -133,154 +204,139 @@ func Run(root string, cfg *config.Config, opt Options) error {
 			continue
 		}
 
+		dir := filepath.Dir(path)
+		fcfg := cfgs[path]
+		requireProvider := map[string]bool{
+			"module":   fcfg.Modules.RequiresProvider(),
+			"resource": fcfg.Resources.RequiresProvider(),
+		}
+		if fcfg.Data != nil {
+			requireProvider["data"] = fcfg.Data.RequiresProvider()
+		}
 		body := file.Body.(*hclsyntax.Body)
-		var blockInfos []blockInfo
 		for _, b := range body.Blocks {
-
 			switch b.Type {
+			case "variable", "output", "module", "resource", "data", "check":
+			default:
+				continue
+			}
 
-			case "variable", "output":
-				info := blockInfo{
-					Kind:       b.Type,
-					StartLine:  b.Range().Start.Line,
-					EndLine:    b.Range().End.Line,
-					SingleLine: b.Range().Start.Line == b.Range().End.Line,
+			typ, old, ok := blockLabels(b)
+			if !ok {
+				continue
+			}
+			addr := blockAddress(b.Type, typ, old)
+			if declared[dir] == nil {
+				declared[dir] = map[string]string{}
+			}
+			declared[dir][addr] = path
+			if b.Type == "module" {
+				if source, ok := moduleSourceString(b); ok {
+					if child, ok := mods.Resolve(dir, old, source); ok {
+						if moduleCalls[dir] == nil {
+							moduleCalls[dir] = map[string]string{}
+						}
+						moduleCalls[dir][old] = child
+					}
 				}
-				blockInfos = append(blockInfos, info)
+			}
 
-				if !opt.allows(b.Type) {
-					continue
-				}
-				if len(b.Labels) == 0 {
-					continue
-				}
-				old := b.Labels[0]
-				rule := map[string]*config.Rule{
-					"variable": &cfg.Variables,
-					"output":   &cfg.Outputs,
-				}[b.Type]
-				if rule.IsIgnored(old) || rule.Matches(old) {
-					continue
-				}
-				newName := toSnake(old)
-				fileRen[path] = append(fileRen[path], rename{old, newName})
-				globalRen[old] = newName
+			if !opt.allows(b.Type) {
+				continue
+			}
+			rule := ruleForKind(fcfg, b.Type)
+			if rule == nil {
+				continue
+			}
+			_, mapped := rev.lookupRename(path, addr)
+			stutter := rule.StutterDisallowed() && config.TypeStutter(typ, old) != ""
+			if mapped || !(rule.IsIgnored(old) || (rule.Matches(old) && !stutter)) {
+				labelRenames = append(labelRenames, labelRename{
+					Path: path,
+					Line: b.DefRange().Start.Line,
+					Kind: b.Type,
+					Type: typ,
+					Old:  old,
+					New:  proposeName(rule, typ, old, path),
+				})
+			}
 
-			case "module":
-				info := blockInfo{
-					Kind:       "module",
-					StartLine:  b.Range().Start.Line,
-					EndLine:    b.Range().End.Line,
-					SingleLine: b.Range().Start.Line == b.Range().End.Line,
-				}
-				blockInfos = append(blockInfos, info)
+			if (b.Type == "variable" || b.Type == "output") && !rule.IsIgnored(old) &&
+				rule.SensitiveRequired(old) && !parser.IsSensitive(b) {
+				sensitiveFixes[path] = append(sensitiveFixes[path], sensitiveEdit(src, b))
+			}
 
-				if !opt.allows("module") {
-					continue
+			if requireProvider[b.Type] && needsProviderAssignment(b, b.Type) {
+				pref := ""
+				if b.Type != "module" {
+					pref = providerTypeFromBlock(b)
 				}
-				if len(b.Labels) == 0 {
-					continue
-				}
-				old := b.Labels[0]
-				if !(cfg.Modules.IsIgnored(old) || cfg.Modules.Matches(old)) {
-					newName := toSnake(old)
-					fileRen[path] = append(fileRen[path], rename{old, newName})
-					globalRen[old] = newName
-				}
-
-				if requireProvider["module"] && opt.allows("module") && needsProviderAssignment(b, "module") {
-					if err := scheduleProviderFix(path, src, b, "module", "", resolver, providerFixes, root); err != nil {
+				pick := providerPick{MostUsed: fcfg.ProviderAssignment.MostUsed()}
+				pick.Alias, _ = fcfg.ProviderAssignment.AliasFor(filepath.ToSlash(relPath(root, path)), typ)
+				if err := scheduleProviderFix(path, src, b, b.Type, pref, pick, resolver, providerFixes, root); err != nil {
+					if !errors.Is(err, errAmbiguousProvider) {
 						return err
 					}
-					hasProviderFixes = true
+					ambiguous = append(ambiguous, fmt.Sprintf("%s:%d %s: %v", relPath(root, path), b.DefRange().Start.Line, addressOfBlock(b), err))
 				}
-				blockInfos = append(blockInfos, blockInfo{
-					Kind:       "module",
-					StartLine:  b.Range().Start.Line,
-					EndLine:    b.Range().End.Line,
-					SingleLine: b.Range().Start.Line == b.Range().End.Line,
-				})
-
-			case "resource":
-				info := blockInfo{
-					Kind:       "resource",
-					StartLine:  b.Range().Start.Line,
-					EndLine:    b.Range().End.Line,
-					SingleLine: b.Range().Start.Line == b.Range().End.Line,
-				}
-				blockInfos = append(blockInfos, info)
+			}
+		}
+	}
 
-				if !opt.allows("resource") {
-					continue
-				}
-				if len(b.Labels) < 2 {
-					continue
-				}
-				old := b.Labels[1]
-				if !(cfg.Resources.IsIgnored(old) || cfg.Resources.Matches(old)) {
-					newName := toSnake(old)
-					fileRen[path] = append(fileRen[path], rename{old, newName})
-					globalRen[old] = newName
-				}
+	if len(ambiguous) > 0 {
+		return fmt.Errorf("ambiguous provider aliases (add a provider_assignment.assign block or set ambiguous = \"most_used\"):\n  %s", strings.Join(ambiguous, "\n  "))
+	}
 
-				if requireProvider["resource"] && opt.allows("resource") && needsProviderAssignment(b, "resource") {
-					pref := providerTypeFromBlock(b)
-					if err := scheduleProviderFix(path, src, b, "resource", pref, resolver, providerFixes, root); err != nil {
-						return err
-					}
-					hasProviderFixes = true
-				}
-				blockInfos = append(blockInfos, blockInfo{
-					Kind:       "resource",
-					StartLine:  b.Range().Start.Line,
-					EndLine:    b.Range().End.Line,
-					SingleLine: b.Range().Start.Line == b.Range().End.Line,
-				})
+	// tags requeridos que se pueden completar con variables (tags.from_variables)
+	var tagFixes map[string][]tagInsertion
+	if opt.allows("tags") {
+		tagFixes = planTagFixes(files, cfgs, declared)
+	}
 
-			case "data":
-				info := blockInfo{
-					Kind:       "data",
-					StartLine:  b.Range().Start.Line,
-					EndLine:    b.Range().End.Line,
-					SingleLine: b.Range().Start.Line == b.Range().End.Line,
-				}
-				blockInfos = append(blockInfos, info)
+	/* ---------- 1️⃣b decisiones registradas / modo interactivo ----------- */
 
-				if !opt.allows("data") {
-					continue
-				}
-				if len(b.Labels) < 2 {
-					continue
-				}
-				old := b.Labels[1]
-				if cfg.Data == nil {
-					continue
-				}
-				if !(cfg.Data.IsIgnored(old) || cfg.Data.Matches(old)) {
-					newName := toSnake(old)
-					fileRen[path] = append(fileRen[path], rename{old, newName})
-					globalRen[old] = newName
-				}
+	labelRenames, err = rev.applyLabelDecisions(labelRenames)
+	if err != nil {
+		return err
+	}
+	labelRenames = resolveCollisions(root, labelRenames, declared, cfg.Fix.OnCollision)
+	labelRenames, err = rev.promptLabels(labelRenames)
+	if err != nil {
+		return err
+	}
+	if err := checkCollisions(root, labelRenames, declared); err != nil {
+		return err
+	}
+	providerFixes, err = rev.reviewProviders(providerFixes)
+	if err != nil {
+		return err
+	}
+	pendingFileRenames, err = rev.reviewFiles(pendingFileRenames, files)
+	if err != nil {
+		return err
+	}
+	if opt.Interactive && rev.changed {
+		if err := decisions.Save(filepath.Join(root, config.RenamesFile)); err != nil {
+			return err
+		}
+		fmt.Printf("recorded decisions in %s\n", filepath.Join(root, config.RenamesFile))
+	}
 
-				if requireProvider["data"] && opt.allows("data") && needsProviderAssignment(b, "data") {
-					pref := providerTypeFromBlock(b)
-					if err := scheduleProviderFix(path, src, b, "data", pref, resolver, providerFixes, root); err != nil {
-						return err
-					}
-					hasProviderFixes = true
-				}
-				blockInfos = append(blockInfos, blockInfo{
-					Kind:       "data",
-					StartLine:  b.Range().Start.Line,
-					EndLine:    b.Range().End.Line,
-					SingleLine: b.Range().Start.Line == b.Range().End.Line,
-				})
-			}
+	// declaraciones sin uso (--remove-unused), después de las inserciones: lo que agregan cuenta como uso
+	var removals map[string][]textEdit
+	var realign map[string][]string
+	removedDecls := 0
+	if opt.RemoveUnused {
+		added := insertionEdits(providerFixes, sensitiveFixes, tagFixes)
+		if removals, realign, removedDecls, err = planRemovals(files, cfgs, mods, added); err != nil {
+			return err
 		}
-		blockInfosByPath[path] = blockInfos
 	}
 
-	if len(globalRen) == 0 && !hasProviderFixes && !hasFileRenames && !spacingEnabled {
+	hasProviderFixes := len(providerFixes) > 0
+	hasFileRenames := len(pendingFileRenames) > 0
+
+	if len(labelRenames) == 0 && !hasProviderFixes && !hasFileRenames && len(tagFixes) == 0 && len(sensitiveFixes) == 0 && removedDecls == 0 && !spacingEnabled && !layoutEnabled && !orderingEnabled {
 		// Alinea el comportamiento con la solicitud de un resumen al final
 		if opt.DryRun {
 			fmt.Println("✅ No fixes needed")
@@ -292,103 +348,144 @@ func Run(root string, cfg *config.Config, opt Options) error {
 		return nil
 	}
 
-	/* ---------- 2️⃣  regex para refs cruzadas (.old  y  module.old) ------- */
-
-	var crossRe *regexp.Regexp
-	if len(globalRen) > 0 {
-		alts := make([]string, 0, len(globalRen))
-		for o := range globalRen {
-			alts = append(alts, regexp.QuoteMeta(o))
-		}
-		sort.Slice(alts, func(i, j int) bool { return len(alts[i]) > len(alts[j]) })
+	/* ---------- 2️⃣  índice de renombres por módulo ----------------------- */
 
-		// Grupo 1 = via `module.<old>`   Grupo 2 = via `.old`
-		crossRe = regexp.MustCompile(`(?:\bmodule\.(?P<mod>` + strings.Join(alts, "|") +
-			`)\b|\.(?P<dot>` + strings.Join(alts, "|") + `)\b)`)
+	index := newRenameIndex(labelRenames, moduleCalls)
+	renamesByPath := map[string][]labelRename{}
+	for _, lr := range labelRenames {
+		renamesByPath[lr.Path] = append(renamesByPath[lr.Path], lr)
 	}
 
 	dmp := diffmatchpatch.New()
+	var staged []stagedFile
+	layoutTargets := map[string][]string{} // archivo destino → bloques movidos hacia él
 
 	/* ---------- 3️⃣  reescritura por archivo ----------------------------- */
 
 	for _, path := range files {
 		orig, _ := ioutil.ReadFile(path)
-		mod := orig
+		var edits []textEdit
 
 		// 3a. providers faltantes
 		if ins := providerFixes[path]; len(ins) > 0 {
-			sort.Slice(ins, func(i, j int) bool { return ins[i].Offset > ins[j].Offset })
 			for _, fix := range ins {
-				mod = insertText(mod, fix.Offset, fix.Payload)
+				edits = append(edits, textEdit{Start: fix.Offset, End: fix.Offset, Text: fix.payload()})
 			}
 			providerAssignments += len(ins)
 		}
+		edits = append(edits, sensitiveFixes[path]...)
+		sensitiveMarked += len(sensitiveFixes[path])
+		for _, tf := range tagFixes[path] {
+			edits = append(edits, tf.Edit)
+			tagsAdded += len(tf.Keys)
+		}
 
-		// 3b. renombres locales
-		if len(fileRen[path]) > 0 {
-			filesWithDecl++
-			declRenames += len(fileRen[path])
+		// 3b. declaraciones y 3c. referencias (por dirección, solo dentro del módulo)
+		if len(labelRenames) > 0 {
+			file, diags := hclsyntax.ParseConfig(orig, path, hcl.Pos{Line: 1, Column: 1})
+			if !diags.HasErrors() {
+				body := file.Body.(*hclsyntax.Body)
+				labels := index.labelEdits(path, orig, body)
+				refs := append(index.referenceEdits(path, orig, body), index.argumentEdits(path, body)...)
+				if len(labels) > 0 {
+					filesWithDecl++
+					declRenames += len(labels)
+				}
+				xrefHits += len(refs)
+				edits = append(edits, labels...)
+				edits = append(edits, refs...)
+			}
+		}
+		if dels := removals[path]; len(dels) > 0 {
+			edits = append(dropInside(edits, dels), dels...)
 		}
-		for _, rn := range fileRen[path] {
-			mod = bytes.ReplaceAll(mod, []byte(rn.Old), []byte(rn.New))
+		mod := applyEdits(orig, edits)
+		if names := realign[path]; len(names) > 0 {
+			mod = alignLocals(path, mod, names)
 		}
 
-		// 3c. referencias cruzadas
-		if crossRe != nil {
-			mod = crossRe.ReplaceAllFunc(mod, func(b []byte) []byte {
-				m := crossRe.FindSubmatch(b)
-				var old string
-				if len(m) >= 2 && len(m[1]) > 0 { // module.<old>
-					old = string(m[1])
-				} else if len(m) >= 3 && len(m[2]) > 0 { // .old
-					old = string(m[2])
-				}
-				if nn, ok := globalRen[old]; ok {
-					xrefHits++
-					return bytes.Replace(b, []byte(old), []byte(nn), 1)
-				}
-				return b
-			})
+		// 3d. bloques fuera del archivo que les asigna el layout
+		if layoutEnabled {
+			var moves []layoutMove
+			mod, moves = extractMisplaced(path, mod, cfgs[path].Layout)
+			for _, mv := range moves {
+				layoutTargets[mv.Target] = append(layoutTargets[mv.Target], mv.Text)
+				layoutMoved++
+			}
+		}
+		if orderingEnabled {
+			var n int
+			mod, n = orderContent(path, mod, cfgs[path].Ordering)
+			reordered += n
 		}
 
-		if cfg.Spacing != nil && cfg.Spacing.EnabledValue() {
-			if updated, changed := enforceBlockSpacing(mod, blockInfosByPath[path], cfg.Spacing, opt); changed {
-				mod = updated
+		if updated, changed := enforceBlockSpacing(path, mod, cfgs[path].Spacing, opt); changed {
+			mod = updated
+		}
+
+		if opt.Moved {
+			for _, lr := range renamesByPath[path] {
+				if lr.Kind != "resource" && lr.Kind != "module" {
+					continue
+				}
+				mod = append(mod, movedBlock(lr)...)
+				movedBlocks++
 			}
 		}
 
 		if bytes.Equal(orig, mod) {
 			continue
 		}
+		staged = append(staged, stagedFile{Path: path, Original: orig, Content: mod})
+	}
 
-		if opt.DryRun {
-			fmt.Printf("\n--- %s\n", path)
-			diff := dmp.DiffMain(string(orig), string(mod), false)
-			fmt.Print(dmp.DiffPrettyText(diff))
-			filesChanged++
-		} else if opt.Write {
-			if err := ioutil.WriteFile(path, mod, 0o644); err != nil {
-				return err
+	if len(layoutTargets) > 0 {
+		if staged, err = stageLayoutTargets(staged, layoutTargets, pendingFileRenames, tree, opt); err != nil {
+			return err
+		}
+	}
+
+	if opt.DryRun {
+		for _, sf := range staged {
+			if sf.Created {
+				fmt.Printf("\n--- %s (new)\n", sf.Path)
+			} else {
+				fmt.Printf("\n--- %s\n", sf.Path)
 			}
-			fmt.Printf("fixed %s\n", path)
+			diff := dmp.DiffMain(string(sf.Original), string(sf.Content), false)
+			fmt.Print(dmp.DiffPrettyText(diff))
 			filesChanged++
 		}
 	}
 
-	// resumen final
-	if len(pendingFileRenames) > 0 {
+	/* ---------- 4️⃣  verificación y commit atómico ---------------------- */
+
+	if opt.DryRun {
 		for _, fr := range pendingFileRenames {
-			if opt.DryRun {
-				fmt.Printf("rename %s -> %s\n", fr.Old, fr.New)
-				fileRenameCount++
-			} else if opt.Write {
-				if err := os.Rename(fr.Old, fr.New); err != nil {
-					return err
-				}
-				fmt.Printf("renamed %s -> %s\n", fr.Old, fr.New)
-				fileRenameCount++
-				filesChanged++
-			}
+			fmt.Printf("rename %s -> %s\n", fr.Old, fr.New)
+			fileRenameCount++
+		}
+		if err := verifyStaged(mods, files, staged); err != nil {
+			return err
+		}
+	} else if opt.Write && (len(staged) > 0 || len(pendingFileRenames) > 0) {
+		if err := verifyStaged(mods, files, staged); err != nil {
+			return err
+		}
+		if err := commitStaged(root, staged, pendingFileRenames); err != nil {
+			return err
+		}
+		if _, err := rescan(root, cfg); err != nil {
+			return err
+		}
+		for _, sf := range staged {
+			fmt.Printf("fixed %s\n", sf.Path)
+			filesChanged++
+		}
+		for _, fr := range pendingFileRenames {
+			fmt.Printf("renamed %s -> %s\n", fr.Old, fr.New)
+			fileRenameCount++
+			filesChanged++
 		}
 	}
 
@@ -401,6 +498,24 @@ func Run(root string, cfg *config.Config, opt Options) error {
 		if fileRenameCount > 0 {
 			fmt.Printf(" Would rename %d files.", fileRenameCount)
 		}
+		if movedBlocks > 0 {
+			fmt.Printf(" Would add %d moved blocks.", movedBlocks)
+		}
+		if tagsAdded > 0 {
+			fmt.Printf(" Would add %d tags.", tagsAdded)
+		}
+		if sensitiveMarked > 0 {
+			fmt.Printf(" Would mark %d blocks sensitive.", sensitiveMarked)
+		}
+		if removedDecls > 0 {
+			fmt.Printf(" Would remove %d unused declarations.", removedDecls)
+		}
+		if layoutMoved > 0 {
+			fmt.Printf(" Would move %d blocks to their layout files.", layoutMoved)
+		}
+		if reordered > 0 {
+			fmt.Printf(" Would reorder %d blocks or block groups.", reordered)
+		}
 		fmt.Printf("\n")
 	} else if opt.Write {
 		fmt.Printf("\nSummary: renamed %d labels across %d files; updated %d files; %d cross-references.",
@@ -411,6 +526,27 @@ func Run(root string, cfg *config.Config, opt Options) error {
 		if fileRenameCount > 0 {
 			fmt.Printf(" Renamed %d files.", fileRenameCount)
 		}
+		if movedBlocks > 0 {
+			fmt.Printf(" Added %d moved blocks.", movedBlocks)
+		}
+		if tagsAdded > 0 {
+			fmt.Printf(" Added %d tags.", tagsAdded)
+		}
+		if sensitiveMarked > 0 {
+			fmt.Printf(" Marked %d blocks sensitive.", sensitiveMarked)
+		}
+		if removedDecls > 0 {
+			fmt.Printf(" Removed %d unused declarations.", removedDecls)
+		}
+		if layoutMoved > 0 {
+			fmt.Printf(" Moved %d blocks to their layout files.", layoutMoved)
+		}
+		if reordered > 0 {
+			fmt.Printf(" Reordered %d blocks or block groups.", reordered)
+		}
+		if filesChanged > 0 {
+			fmt.Printf(" Undo with `tfsuit fix --undo`.")
+		}
 		fmt.Printf("\n")
 	}
 	return nil
@@ -440,6 +576,81 @@ func collectTfFiles(root string) ([]string, error) {
 	return out, err
 }
 
+// blockLabels returns the type label (resource/data only) and the name label of a block.
+func blockLabels(b *hclsyntax.Block) (string, string, bool) {
+	switch b.Type {
+	case "resource", "data":
+		if len(b.Labels) < 2 {
+			return "", "", false
+		}
+		return b.Labels[0], b.Labels[1], true
+	default:
+		if len(b.Labels) == 0 {
+			return "", "", false
+		}
+		return "", b.Labels[0], true
+	}
+}
+
+// proposeName is the label fix suggests for old: without the repeated type when the rule
+// disallows stutter, then filled from the rule template when possible, snake_case otherwise.
+func proposeName(rule *config.Rule, typ, old, path string) string {
+	if rule.StutterDisallowed() && typ != "" {
+		old = config.StripStutter(typ, old)
+	}
+	if rule.Template != "" {
+		if name, ok := rule.SuggestName(old, path); ok {
+			return name
+		}
+	}
+	return toSnake(old)
+}
+
+func ruleForKind(cfg *config.Config, kind string) *config.Rule {
+	switch kind {
+	case "variable":
+		return cfg.Variables
+	case "output":
+		return cfg.Outputs
+	case "module":
+		return cfg.Modules
+	case "resource":
+		return cfg.Resources
+	case "data":
+		return cfg.Data
+	case "check":
+		return cfg.Checks
+	case "file":
+		return cfg.Files
+	}
+	return nil
+}
+
+// blockAddress renders the Terraform address used in rename maps and prompts.
+func blockAddress(kind, typ, name string) string {
+	switch kind {
+	case "variable":
+		return "var." + name
+	case "output":
+		return "output." + name
+	case "module":
+		return "module." + name
+	case "data":
+		return "data." + typ + "." + name
+	case "resource":
+		return typ + "." + name
+	}
+	return kind + "." + name
+}
+
+func addressOfBlock(b *hclsyntax.Block) string {
+	typ, name, ok := blockLabels(b)
+	if !ok {
+		return b.Type
+	}
+	return blockAddress(b.Type, typ, name)
+}
+
 func needsProviderAssignment(block *hclsyntax.Block, kind string) bool {
 	switch kind {
 	case "module":
@@ -470,23 +681,27 @@ func providerTypeFromBlock(block *hclsyntax.Block) string {
 	return raw
 }
 
-func scheduleProviderFix(path string, src []byte, block *hclsyntax.Block, kind, preferredType string, resolver *providerResolver, providerFixes map[string][]providerInsertion, root string) error {
+func scheduleProviderFix(path string, src []byte, block *hclsyntax.Block, kind, preferredType string, pick providerPick, resolver *providerResolver, providerFixes map[string][]providerInsertion, root string) error {
 	dir := filepath.Dir(path)
 	offset := block.CloseBraceRange.Start.Byte
-	indent := indentAt(src, offset)
+	ins := providerInsertion{
+		Offset:  offset,
+		Path:    path,
+		Line:    block.DefRange().Start.Line,
+		Address: addressOfBlock(block),
+		Kind:    kind,
+		Indent:  indentAt(src, offset),
+	}
 
 	if kind == "module" {
 		if aliases := resolver.requiredAliasesForModule(path, block); len(aliases) > 0 {
-			payload := renderModuleProvidersPayload(indent, aliases)
-			providerFixes[path] = append(providerFixes[path], providerInsertion{
-				Offset:  offset,
-				Payload: payload,
-			})
+			ins.Aliases = aliases
+			providerFixes[path] = append(providerFixes[path], ins)
 			return nil
 		}
 	}
 
-	sel, err := resolver.resolve(dir, preferredType)
+	sel, err := resolver.resolve(dir, preferredType, pick)
 	if err != nil {
 		if errors.Is(err, errNoProviderDefinitions) {
 			if err := ensureProvidersFile(root); err != nil {
@@ -496,14 +711,35 @@ func scheduleProviderFix(path string, src []byte, block *hclsyntax.Block, kind,
 		}
 		return err
 	}
-	payload := renderProviderPayload(kind, indent, sel)
-	providerFixes[path] = append(providerFixes[path], providerInsertion{
-		Offset:  offset,
-		Payload: payload,
-	})
+	ins.Alias = sel.Alias
+	providerFixes[path] = append(providerFixes[path], ins)
 	return nil
 }
 
+// sensitiveEdit sets sensitive = true on a variable or output, in place of a literal false.
+func sensitiveEdit(src []byte, block *hclsyntax.Block) textEdit {
+	if attr, ok := block.Body.Attributes["sensitive"]; ok {
+		rng := attr.Expr.Range()
+		return textEdit{Start: rng.Start.Byte, End: rng.End.Byte, Text: "true"}
+	}
+	offset := block.CloseBraceRange.Start.Byte
+	indent := indentAt(src, offset)
+	return insertBefore(src, offset, indent, indent+"  sensitive = true\n")
+}
+
+// insertBefore puts lines right before the closing brace at offset: at the start of its line when
+// the brace opens it, otherwise on new lines between the brace and what precedes it.
+func insertBefore(src []byte, offset int, indent, lines string) textEdit {
+	at := offset
+	for at > 0 && (src[at-1] == ' ' || src[at-1] == '\t') {
+		at--
+	}
+	if at == 0 || src[at-1] == '\n' {
+		return textEdit{Start: at, End: at, Text: lines}
+	}
+	return textEdit{Start: offset, End: offset, Text: "\n" + lines + indent}
+}
+
 func indentAt(src []byte, offset int) string {
 	if offset > len(src) {
 		offset = len(src)
@@ -562,20 +798,6 @@ func renderModuleProvidersPayload(indent string, aliases []string) string {
 	return sb.String()
 }
 
-func insertText(src []byte, offset int, payload string) []byte {
-	if offset < 0 {
-		offset = 0
-	}
-	if offset > len(src) {
-		offset = len(src)
-	}
-	buf := make([]byte, 0, len(src)+len(payload))
-	buf = append(buf, src[:offset]...)
-	buf = append(buf, payload...)
-	buf = append(buf, src[offset:]...)
-	return buf
-}
-
 func ensureProvidersFile(root string) error {
 	path := filepath.Join(root, "providers.tf")
 	if _, err := os.Stat(path); err == nil {
@@ -594,10 +816,7 @@ func ensureProvidersFile(root string) error {
 	return os.WriteFile(path, []byte(content), 0o644)
 }
 
-func planFileRenames(files []string, rule *config.Rule) []fileRename {
-	if rule == nil {
-		return nil
-	}
+func planFileRenames(files []string, cfgs map[string]*config.Config) []fileRename {
 	existing := make(map[string]struct{}, len(files))
 	for _, path := range files {
 		existing[path] = struct{}{}
@@ -605,6 +824,10 @@ func planFileRenames(files []string, rule *config.Rule) []fileRename {
 
 	var renames []fileRename
 	for _, path := range files {
+		rule := cfgs[path].Files
+		if rule == nil {
+			continue
+		}
 		base := filepath.Base(path)
 		if rule.IsIgnored(base) || rule.Matches(base) {
 			continue
@@ -638,62 +861,59 @@ func planFileRenames(files []string, rule *config.Rule) []fileRename {
 	return renames
 }
 
-func enforceBlockSpacing(content []byte, infos []blockInfo, spacing *config.BlockSpacing, opt Options) ([]byte, bool) {
-	if spacing == nil || !spacing.EnabledValue() || len(infos) < 2 || !opt.allows("spacing") {
-		return content, false
-	}
-	lines := splitLines(content)
-	var ops []struct {
-		insertLine int
-		count      int
-	}
-
-	for i := 0; i < len(infos)-1; i++ {
-		current := infos[i]
-		next := infos[i+1]
-		if spacing.AllowCompactKind(current.Kind) && spacing.AllowCompactKind(next.Kind) &&
-			current.SingleLine && next.SingleLine {
-			continue
+// topLevelBlockInfos returns the positions of the top-level blocks of body.
+func topLevelBlockInfos(body *hclsyntax.Body) []blockInfo {
+	var infos []blockInfo
+	for _, b := range body.Blocks {
+		typ, name, _ := blockLabels(b)
+		key := name
+		if typ != "" {
+			key = typ + "." + name
 		}
-		actual := countBlankLinesBetween(lines, current.EndLine, next.StartLine)
-		if actual >= spacing.MinLines() {
-			continue
-		}
-		ops = append(ops, struct {
-			insertLine int
-			count      int
-		}{
-			insertLine: next.StartLine,
-			count:      spacing.MinLines() - actual,
+		infos = append(infos, blockInfo{
+			Kind:      b.Type,
+			Key:       key,
+			StartLine: b.Range().Start.Line,
+			EndLine:   b.Range().End.Line,
 		})
 	}
+	return infos
+}
 
-	if len(ops) == 0 {
+// enforceBlockSpacing fixes the gaps parser.SpacingIssues reports: it adds the missing blank
+// lines above the comments of the next block or item and drops those beyond max_blank_lines.
+func enforceBlockSpacing(path string, content []byte, spacing *config.BlockSpacing, opt Options) ([]byte, bool) {
+	if spacing == nil || !spacing.EnabledValue() || !opt.allows("spacing") {
 		return content, false
 	}
-
-	sort.Slice(ops, func(i, j int) bool {
-		return ops[i].insertLine > ops[j].insertLine
-	})
-
-	for _, op := range ops {
-		idx := op.insertLine - 1
-		if idx < 0 {
-			idx = 0
-		}
-		blanks := make([]string, op.count)
-		if idx >= len(lines) {
-			lines = append(lines, blanks...)
-			continue
+	body, ok := parseBody(path, content)
+	if !ok {
+		return content, false
+	}
+	issues := parser.SpacingIssues(content, body, spacing)
+	if len(issues) == 0 {
+		return content, false
+	}
+	insert := map[int]int{}
+	drop := map[int]bool{}
+	for _, issue := range issues {
+		insert[issue.Insert] += issue.Missing
+		for _, line := range issue.Extra {
+			drop[line] = true
 		}
-		lines = append(lines[:idx], append(blanks, lines[idx:]...)...)
 	}
-
-	result := strings.Join(lines, "\n")
-	if strings.HasSuffix(string(content), "\n") {
-		result += "\n"
+	lines := splitLines(content)
+	out := make([]string, 0, len(lines))
+	for i, line := range lines {
+		for n := insert[i+1]; n > 0; n-- {
+			out = append(out, "")
+		}
+		if !drop[i+1] {
+			out = append(out, line)
+		}
 	}
-	return []byte(result), true
+	// splitLines deja "" tras el último salto: unirlas conserva el final del archivo
+	return joinLines(out), true
 }
 
 func splitLines(src []byte) []string {
@@ -711,25 +931,9 @@ func splitLines(src []byte) []string {
 	return lines
 }
 
-func countBlankLinesBetween(lines []string, endLine, nextStart int) int {
-	if nextStart <= endLine+1 {
-		return 0
-	}
-	blank := 0
-	for lineNum := endLine + 1; lineNum <= nextStart-1; lineNum++ {
-		idx := lineNum - 1
-		if idx < 0 || idx >= len(lines) {
-			continue
-		}
-		if strings.TrimSpace(lines[idx]) == "" {
-			blank++
-		}
-	}
-	return blank
-}
-
 type providerResolver struct {
 	root       string
+	modules    *modules.Resolver
 	scopes     map[string]*providerScope
 	aliasUsage map[string]int
 	moduleDirs []string
@@ -748,9 +952,10 @@ type providerAlias struct {
 	Defined bool
 }
 
-func buildProviderResolver(root string, files []string) (*providerResolver, error) {
+func buildProviderResolver(root string, files []string, mods *modules.Resolver) (*providerResolver, error) {
 	resolver := &providerResolver{
 		root:       root,
+		modules:    mods,
 		scopes:     map[string]*providerScope{},
 		aliasUsage: map[string]int{},
 	}
@@ -818,7 +1023,7 @@ func buildProviderResolver(root string, files []string) (*providerResolver, erro
 				if attr, ok := b.Body.Attributes["source"]; ok {
 					if val, diags := attr.Expr.Value(nil); diags == nil || !diags.HasErrors() {
 						if val.Type() == cty.String {
-							if child, ok := resolveModuleSource(root, dir, val.AsString()); ok {
+							if child, ok := mods.Resolve(dir, b.Labels[0], val.AsString()); ok {
 								call.childDir = child
 								moduleDirSet[child] = struct{}{}
 							}
@@ -967,7 +1172,13 @@ func (s *providerScope) allowAlias(name string) {
 	s.addAlias(name, false)
 }
 
-func (r *providerResolver) resolve(dir, preferredType string) (providerSelection, error) {
+// providerPick carries the provider_assignment decision for one block.
+type providerPick struct {
+	Alias    string // assigned alias, if an assign block matched
+	MostUsed bool   // break ties by usage instead of reporting them
+}
+
+func (r *providerResolver) resolve(dir, preferredType string, pick providerPick) (providerSelection, error) {
 	scopePath := r.scopeForDir(dir)
 	scope := r.scope(scopePath)
 
@@ -979,10 +1190,27 @@ func (r *providerResolver) resolve(dir, preferredType string) (providerSelection
 		return providerSelection{}, errNoProviderDefinitions
 	}
 
+	if pick.Alias != "" {
+		for _, c := range candidates {
+			if c.Name == pick.Alias {
+				return providerSelection{Alias: c.Name, Type: c.Type}, nil
+			}
+		}
+		return providerSelection{}, fmt.Errorf("provider_assignment assigns %s, which is not available in %s", pick.Alias, scopePath)
+	}
+
 	filtered := filterAliasesByType(candidates, preferredType)
 	if len(filtered) > 0 {
 		candidates = filtered
 	}
+	if len(candidates) > 1 && !pick.MostUsed {
+		names := make([]string, len(candidates))
+		for i, c := range candidates {
+			names[i] = c.Name
+		}
+		sort.Strings(names)
+		return providerSelection{}, fmt.Errorf("%w between %s", errAmbiguousProvider, strings.Join(names, ", "))
+	}
 
 	sort.SliceStable(candidates, func(i, j int) bool {
 		ci := r.aliasUsage[candidates[i].Name]
@@ -1010,12 +1238,18 @@ func (r *providerResolver) requiredAliasesForModule(filePath string, block *hcls
 		return nil
 	}
 	parentDir := filepath.Dir(filePath)
-	childDir, ok := resolveModuleSource(r.root, parentDir, source)
+	childDir, ok := r.modules.Resolve(parentDir, block.Labels[0], source)
 	if !ok {
 		return nil
 	}
-	scope := r.scope(childDir)
-	return scope.allowedAliasList()
+	if aliases := r.scope(childDir).allowedAliasList(); len(aliases) > 0 {
+		return aliases
+	}
+	// módulos remotos no se escanean: sus configuration_aliases salen de la copia local
+	if mi, err := modules.Read(childDir); err == nil {
+		return mi.Aliases
+	}
+	return nil
 }
 
 func (s *providerScope) collectAliases() []*providerAlias {
@@ -1072,33 +1306,6 @@ func providerTypeFromAlias(alias string) string {
 	return alias
 }
 
-func resolveModuleSource(root, parentDir, source string) (string, bool) {
-	if source == "" {
-		return "", false
-	}
-
-	if strings.Contains(source, "::") {
-		return "", false
-	}
-
-	var target string
-	if filepath.IsAbs(source) {
-		target = source
-	} else {
-		target = filepath.Join(parentDir, source)
-	}
-	target = filepath.Clean(target)
-
-	if !strings.HasPrefix(target, root) {
-		return "", false
-	}
-	info, err := os.Stat(target)
-	if err != nil || !info.IsDir() {
-		return "", false
-	}
-	return target, true
-}
-
 func objectKeyToString(expr hclsyntax.Expression) string {
 	if expr == nil {
 		return ""
@@ -1236,7 +1443,3 @@ func traversalToProviderRef(tr hcl.Traversal) string {
 	}
 	return strings.Join(parts, ".")
 }
-
-func ScanFileAfterFix(path string, cfg *config.Config) ([]model.Finding, error) {
-	return parser.ParseFile(path, cfg)
-}
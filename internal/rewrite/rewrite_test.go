@@ -282,18 +282,222 @@ resource "aws_iam_role" "app" {}
 	}
 }
 
-func TestScanFileAfterFix(t *testing.T) {
-	cfg, err := config.Load(filepath.Join("..", "..", "samples", "simple", "tfsuit.hcl"))
+func TestFixProviderAssignment(t *testing.T) {
+	dir := t.TempDir()
+	rules := `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources {
+  pattern          = ".*"
+  require_provider = true
+}
+`
+	writeFile(t, filepath.Join(dir, "providers.tf"), `provider "aws" { alias = "us_east_1" }
+
+provider "aws" { alias = "eu_west_1" }
+
+provider "aws" {
+  alias = "us_east_1_ops"
+}
+`)
+	writeFile(t, filepath.Join(dir, "eu/main.tf"), `resource "aws_s3_bucket" "logs" {}
+`)
+	writeFile(t, filepath.Join(dir, "us.tf"), `resource "aws_s3_bucket" "data" {}
+`)
+
+	// sin assign que aplique, la elección es ambigua y fix no adivina
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), rules+`
+provider_assignment {}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	err = rewrite.Run(dir, cfg, rewrite.Options{Write: true})
+	if err == nil || !strings.Contains(err.Error(), "aws_s3_bucket.logs: ambiguous provider between aws.eu_west_1, aws.us_east_1, aws.us_east_1_ops") {
+		t.Fatalf("expected ambiguous provider error, got %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), rules+`
+provider_assignment {
+  assign "aws.eu_west_1" {
+    directories = ["eu"]
+  }
+  assign "aws.us_east_1" {
+    files = ["*.tf"]
+    types = ["aws_"]
+  }
+}
+`)
+	if cfg, err = config.Load(filepath.Join(dir, "tfsuit.hcl")); err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	eu, _ := os.ReadFile(filepath.Join(dir, "eu/main.tf"))
+	if !strings.Contains(string(eu), "provider = aws.eu_west_1") {
+		t.Fatalf("eu resource not assigned to eu_west_1:\n%s", eu)
+	}
+	us, _ := os.ReadFile(filepath.Join(dir, "us.tf"))
+	if !strings.Contains(string(us), "provider = aws.us_east_1\n") {
+		t.Fatalf("us resource not assigned to us_east_1:\n%s", us)
+	}
+}
+
+func TestFixResolvesRegistryModules(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `provider "aws" {
+  alias = "east"
+}
+
+module "vpc" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+`)
+	writeFile(t, filepath.Join(dir, ".terraform/modules/modules.json"), `{"Modules":[{"Key":"","Source":"","Dir":"."},{"Key":"vpc","Source":"registry.terraform.io/terraform-aws-modules/vpc/aws","Dir":".terraform/modules/vpc"}]}`)
+	writeFile(t, filepath.Join(dir, ".terraform/modules/vpc/main.tf"), `terraform {
+  required_providers {
+    aws = {
+      configuration_aliases = [aws.east]
+    }
+  }
+}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	out, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+	if !strings.Contains(string(out), "aws.east = aws.east") {
+		t.Fatalf("registry module missing providers mapping from its configuration_aliases:\n%s", out)
+	}
+}
+
+func TestFixRenamesModuleArguments(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = "^[a-z0-9_]+$" }
+outputs   { pattern = ".*" }
+modules   {
+  pattern          = ".*"
+  require_provider = false
+}
+resources { pattern = ".*" }
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `module "app" {
+  source        = "./modules/app"
+  Instance-Type = "t3.micro"
+}
+`)
+	writeFile(t, filepath.Join(dir, "modules/app/variables.tf"), `variable "Instance-Type" {}
+
+output "type" {
+  value = var.Instance-Type
+}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	out, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+	if !strings.Contains(string(out), "  instance_type = \"t3.micro\"") {
+		t.Fatalf("caller argument not renamed:\n%s", out)
+	}
+	child, _ := os.ReadFile(filepath.Join(dir, "modules/app/variables.tf"))
+	if !strings.Contains(string(child), `variable "instance_type"`) || !strings.Contains(string(child), "var.instance_type") {
+		t.Fatalf("child variable not renamed:\n%s", child)
+	}
+}
+
+func TestBuildGraph(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = "^[a-z_]+$" }
+resources { pattern = ".*" }
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `provider "aws" {
+  alias = "east"
+}
+
+module "network" {
+  source    = "./network"
+  providers = { aws.main = aws.east }
+}
+
+module "App" {
+  source    = "./network"
+  providers = { aws.main = aws.east }
+  vpc       = module.network.vpc_id
+}
+`)
+	writeFile(t, filepath.Join(dir, "network/main.tf"), `terraform {
+  required_providers {
+    aws = {
+      configuration_aliases = [aws.main]
+    }
+  }
+}
+
+variable "vpc" {
+  default = ""
+}
+
+resource "aws_vpc" "this" {
+  provider = aws.main
+}
+
+output "vpc_id" {
+  value = aws_vpc.this.id
+}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
 	if err != nil {
 		t.Fatalf("load cfg: %v", err)
 	}
-	path := filepath.Join("..", "..", "samples", "simple", "bad.tf")
-	findings, err := rewrite.ScanFileAfterFix(path, cfg)
+	g, err := rewrite.BuildGraph(dir, cfg, rewrite.GraphOptions{Resources: true})
 	if err != nil {
-		t.Fatalf("ScanFileAfterFix: %v", err)
+		t.Fatalf("graph: %v", err)
+	}
+	edges := map[string]bool{}
+	for _, e := range g.Edges {
+		edges[e.Kind+" "+e.From+" -> "+e.To] = true
+	}
+	for _, want := range []string{
+		"source module:.:module.network -> dir:network",
+		"providers provider:.:aws.east -> provider:network:aws.main",
+		"provider resource:network:aws_vpc.this -> provider:network:aws.main",
+		"reference module:.:module.App -> module:.:module.network",
+		"declares dir:network -> resource:network:aws_vpc.this",
+	} {
+		if !edges[want] {
+			t.Errorf("missing edge %q in %v", want, edges)
+		}
+	}
+	for _, n := range g.Nodes {
+		flagged := len(n.Violations) > 0
+		if flagged != (n.ID == "module:.:module.App") {
+			t.Errorf("node %s violations: %v", n.ID, n.Violations)
+		}
 	}
-	if len(findings) == 0 {
-		t.Fatalf("expected findings from sample bad.tf")
+	out, err := g.Format("dot")
+	if err != nil || !strings.Contains(out, `"module:.:module.App" [label="module.App", shape=component, color=red`) {
+		t.Fatalf("unexpected dot output (%v):\n%s", err, out)
 	}
 }
 
@@ -518,6 +722,1022 @@ module "b" {}
 	}
 }
 
+func TestFixCollapsesBlankLinesAndSpacesNestedBlocks(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "tfsuit.hcl")
+	if err := os.WriteFile(cfgPath, []byte(`
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources {
+  pattern          = ".*"
+  require_provider = false
+}
+
+block_spacing {
+  max_blank_lines = 1
+  nested { blocks = ["lifecycle"] }
+}
+`), 0o644); err != nil {
+		t.Fatalf("write cfg: %v", err)
+	}
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+
+	tf := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(tf, []byte(`locals {
+  name = "logs"
+}
+# bucket de logs
+resource "aws_s3_bucket" "logs" {
+  bucket = local.name
+
+
+  force_destroy = true
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+
+
+
+terraform {}
+`), 0o644); err != nil {
+		t.Fatalf("write tf: %v", err)
+	}
+
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true}); err != nil {
+		t.Fatalf("spacing fix: %v", err)
+	}
+	out, err := os.ReadFile(tf)
+	if err != nil {
+		t.Fatalf("read tf: %v", err)
+	}
+	want := `locals {
+  name = "logs"
+}
+
+# bucket de logs
+resource "aws_s3_bucket" "logs" {
+  bucket = local.name
+
+  force_destroy = true
+
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+
+terraform {}
+`
+	if string(out) != want {
+		t.Fatalf("unexpected spacing fix:\n%s", out)
+	}
+}
+
+func TestFixInteractiveRecordsDecisions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = "^[a-z0-9_]+$" }
+outputs   { pattern = "^[a-z0-9_]+$" }
+modules   { pattern = "^[a-z0-9_]+$" }
+resources { pattern = "^[a-z0-9_]+$" }
+`)
+	mainTf := filepath.Join(dir, "main.tf")
+	writeFile(t, mainTf, `resource "aws_db_instance" "DBInstance" {}
+
+resource "aws_s3_bucket" "Logs-Bucket" {}
+
+output "db" {
+  value = aws_db_instance.DBInstance.id
+}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+
+	// invalid custom name is rejected and asked again, then the second label is skipped
+	answers := strings.NewReader("Bad-Name\ndb_instance\ns\n")
+	opts := rewrite.Options{Write: true, Interactive: true, Input: answers}
+	if err := rewrite.Run(dir, cfg, opts); err != nil {
+		t.Fatalf("interactive fix: %v", err)
+	}
+	out, _ := os.ReadFile(mainTf)
+	if !strings.Contains(string(out), `"db_instance"`) || !strings.Contains(string(out), "aws_db_instance.db_instance.id") {
+		t.Fatalf("custom name not applied:\n%s", out)
+	}
+	if !strings.Contains(string(out), `"Logs-Bucket"`) {
+		t.Fatalf("skipped label should be untouched:\n%s", out)
+	}
+
+	recorded, err := config.LoadRenameMap(filepath.Join(dir, config.RenamesFile))
+	if err != nil {
+		t.Fatalf("load decisions: %v", err)
+	}
+	if recorded.Renames["aws_db_instance.DBInstance"] != "db_instance" {
+		t.Fatalf("custom decision not recorded: %v", recorded.Renames)
+	}
+	if recorded.Renames["aws_s3_bucket.Logs-Bucket"] != "Logs-Bucket" {
+		t.Fatalf("skip decision not recorded: %v", recorded.Renames)
+	}
+
+	// a non-interactive rerun replays the skip instead of renaming
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true}); err != nil {
+		t.Fatalf("rerun: %v", err)
+	}
+	out, _ = os.ReadFile(mainTf)
+	if !strings.Contains(string(out), `"Logs-Bucket"`) {
+		t.Fatalf("recorded skip not honored on rerun:\n%s", out)
+	}
+}
+
+func TestFixInteractiveFailsWhenInputEnds(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.tf"), `variable "Bad-Name" {}`)
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = "^[a-z0-9_]+$" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	opts := rewrite.Options{Write: true, Interactive: true, Input: strings.NewReader("")}
+	if err := rewrite.Run(dir, cfg, opts); err == nil {
+		t.Fatalf("expected error when no answers are available")
+	}
+	if _, err := os.Stat(filepath.Join(dir, config.RenamesFile)); !os.IsNotExist(err) {
+		t.Fatalf("decisions file should not be written on abort")
+	}
+}
+
+func TestFixRenameMapPropagatesReferences(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = "^[a-z0-9_]+$" }
+outputs   { pattern = "^[a-z0-9_]+$" }
+modules   {
+  pattern          = "^[a-z0-9_]+$"
+  require_provider = false
+}
+resources { pattern = "^[a-z0-9_]+$" }
+
+renames = {
+  "module.app" = "application"
+}
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `resource "aws_instance" "web" {
+  tags = { Name = "web" }
+}
+
+resource "aws_eip" "web" {
+  instance = aws_instance.web.id
+}
+
+module "app" {
+  source = "./app"
+  ip     = aws_eip.web.public_ip
+}
+`)
+	writeFile(t, filepath.Join(dir, "outputs.tf"), `output "web_id" {
+  value = "${aws_instance.web.id}-${module.app.endpoint}"
+}
+`)
+	writeFile(t, filepath.Join(dir, "app/main.tf"), `variable "ip" {}
+
+output "endpoint" {
+  value = var.ip
+}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	renames := &config.RenameMap{
+		Renames: map[string]string{
+			"aws_instance.web":    "web_primary",
+			"app:output.endpoint": "url",
+		},
+	}
+	opts := rewrite.Options{Write: true, RenameMap: renames, Moved: true}
+	if err := rewrite.Run(dir, cfg, opts); err != nil {
+		t.Fatalf("fix with rename map: %v", err)
+	}
+
+	mainTf, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+	for _, want := range []string{
+		`resource "aws_instance" "web_primary"`,
+		`tags = { Name = "web" }`,
+		`resource "aws_eip" "web"`,
+		`instance = aws_instance.web_primary.id`,
+		`ip     = aws_eip.web.public_ip`,
+		`module "application"`,
+		"from = aws_instance.web\n  to   = aws_instance.web_primary",
+		"from = module.app\n  to   = module.application",
+	} {
+		if !strings.Contains(string(mainTf), want) {
+			t.Fatalf("main.tf missing %q:\n%s", want, mainTf)
+		}
+	}
+	outputsTf, _ := os.ReadFile(filepath.Join(dir, "outputs.tf"))
+	if !strings.Contains(string(outputsTf), "${aws_instance.web_primary.id}-${module.application.url}") {
+		t.Fatalf("references not propagated:\n%s", outputsTf)
+	}
+	child, _ := os.ReadFile(filepath.Join(dir, "app/main.tf"))
+	if !strings.Contains(string(child), `output "url"`) {
+		t.Fatalf("child output not renamed:\n%s", child)
+	}
+}
+
+func TestFixKeepsMovedAndImportAddressesInSync(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = "^[a-z0-9_]+$" }
+outputs   { pattern = "^[a-z0-9_]+$" }
+modules   {
+  pattern          = "^[a-z0-9_]+$"
+  require_provider = false
+}
+resources { pattern = "^[a-z0-9_]+$" }
+checks    { pattern = "^[a-z0-9_]+$" }
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `resource "aws_s3_bucket" "Logs" {
+  bucket = "logs"
+}
+
+module "Net" {
+  source = "./net"
+}
+
+moved {
+  from = aws_s3_bucket.old
+  to   = aws_s3_bucket.Logs
+}
+
+moved {
+  from = module.Net.aws_subnet.old
+  to   = module.Net.aws_subnet.Main
+}
+
+import {
+  for_each = toset(["a"])
+  to       = module.Net.aws_subnet.Main
+  id       = each.key
+}
+
+removed {
+  from = aws_s3_bucket.gone
+}
+
+check "Bucket-Health" {
+  assert {
+    condition     = aws_s3_bucket.Logs.bucket != ""
+    error_message = "bucket"
+  }
+}
+`)
+	writeFile(t, filepath.Join(dir, "net/main.tf"), `resource "aws_subnet" "Main" {}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+
+	mainTf, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+	for _, want := range []string{
+		"from = aws_s3_bucket.old\n  to   = aws_s3_bucket.logs",
+		"from = module.net.aws_subnet.old\n  to   = module.net.aws_subnet.main",
+		"to       = module.net.aws_subnet.main",
+		"from = aws_s3_bucket.gone",
+		`check "bucket_health"`,
+		"condition     = aws_s3_bucket.logs.bucket",
+	} {
+		if !strings.Contains(string(mainTf), want) {
+			t.Fatalf("main.tf missing %q:\n%s", want, mainTf)
+		}
+	}
+}
+
+func TestFixRenameMapRejectsCollisions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`)
+	mainTf := filepath.Join(dir, "main.tf")
+	original := `resource "aws_instance" "web" {}
+
+resource "aws_instance" "api" {}
+`
+	writeFile(t, mainTf, original)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	renames := &config.RenameMap{Renames: map[string]string{"aws_instance.web": "api"}}
+	err = rewrite.Run(dir, cfg, rewrite.Options{Write: true, RenameMap: renames})
+	if err == nil || !strings.Contains(err.Error(), "aws_instance.api") {
+		t.Fatalf("expected collision error, got %v", err)
+	}
+	out, _ := os.ReadFile(mainTf)
+	if string(out) != original {
+		t.Fatalf("file must stay untouched on collision:\n%s", out)
+	}
+}
+
+func TestFixGeneratedNameCollisions(t *testing.T) {
+	tf := `resource "aws_instance" "Web-App" {}
+
+resource "aws_instance" "web_app" {}
+
+resource "aws_instance" "WEB_APP" {}
+
+output "ids" {
+  value = [aws_instance.Web-App.id, aws_instance.WEB_APP.id]
+}
+`
+	cfgFor := func(strategy string) string {
+		return `
+variables { pattern = "^[a-z0-9_]+$" }
+outputs   { pattern = "^[a-z0-9_]+$" }
+modules   { pattern = "^[a-z0-9_]+$" }
+resources { pattern = "^[a-z0-9_]+$" }
+
+fix {
+  on_collision = "` + strategy + `"
+}
+`
+	}
+	run := func(strategy string) (string, error) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "tfsuit.hcl"), cfgFor(strategy))
+		writeFile(t, filepath.Join(dir, "main.tf"), tf)
+		cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+		if err != nil {
+			t.Fatalf("load cfg: %v", err)
+		}
+		err = rewrite.Run(dir, cfg, rewrite.Options{Write: true})
+		out, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+		return string(out), err
+	}
+
+	out, err := run("error")
+	if err == nil || !strings.Contains(err.Error(), "aws_instance.web_app") {
+		t.Fatalf("expected collision error, got %v", err)
+	}
+	if out != tf {
+		t.Fatalf("error strategy must not touch files:\n%s", out)
+	}
+
+	out, err = run("suffix")
+	if err != nil {
+		t.Fatalf("suffix strategy: %v", err)
+	}
+	for _, want := range []string{`"web_app_1"`, `"web_app_2"`, `[aws_instance.web_app_1.id, aws_instance.web_app_2.id]`} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("suffix strategy missing %s:\n%s", want, out)
+		}
+	}
+
+	out, err = run("skip")
+	if err != nil {
+		t.Fatalf("skip strategy: %v", err)
+	}
+	if !strings.Contains(out, `"Web-App"`) || !strings.Contains(out, `"WEB_APP"`) {
+		t.Fatalf("skip strategy should keep conflicting labels:\n%s", out)
+	}
+}
+
+func TestFixFillsTemplateSegments(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources {
+  template = "{env}_{service}"
+
+  segment "env" {
+    values = ["dev", "prd"]
+  }
+}
+`)
+	live := filepath.Join(dir, "prd")
+	if err := os.Mkdir(live, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeFile(t, filepath.Join(live, "main.tf"), `resource "aws_s3_bucket" "billing_dev" {}
+
+resource "aws_s3_bucket" "Logs" {}
+
+output "ids" {
+  value = [aws_s3_bucket.billing_dev.id, aws_s3_bucket.Logs.id]
+}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true, FixKinds: map[string]bool{"resource": true}}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	out, _ := os.ReadFile(filepath.Join(live, "main.tf"))
+	for _, want := range []string{`"dev_billing"`, `"prd_logs"`, `[aws_s3_bucket.dev_billing.id, aws_s3_bucket.prd_logs.id]`} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("missing %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestFixStripsTypeStutter(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources {
+  pattern               = "^[a-z_]+$"
+  disallow_type_stutter = true
+}
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `resource "aws_s3_bucket" "logs_s3_bucket" {}
+
+resource "aws_iam_role" "lambda_iam_role" {}
+
+resource "aws_iam_role" "role_admin" {}
+
+output "ids" {
+  value = [aws_s3_bucket.logs_s3_bucket.id, aws_iam_role.lambda_iam_role.id, aws_iam_role.role_admin.id]
+}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true, FixKinds: map[string]bool{"resource": true}}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	out, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+	for _, want := range []string{`"aws_s3_bucket" "logs"`, `"aws_iam_role" "lambda"`, `"aws_iam_role" "role_admin"`, `[aws_s3_bucket.logs.id, aws_iam_role.lambda.id, aws_iam_role.role_admin.id]`} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("missing %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestFixInsertsRequiredTags(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+
+tags {
+  required       = ["Owner", "CostCenter", "Team"]
+  types          = ["aws_*"]
+  from_variables = { Owner = "owner", CostCenter = "cost_center", Team = "team" }
+}
+`)
+	writeFile(t, filepath.Join(dir, "variables.tf"), `variable "owner" {}
+
+variable "cost_center" {}
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+}
+
+resource "aws_s3_bucket" "data" {
+  tags = {
+    Name = "data"
+  }
+}
+
+resource "aws_s3_bucket" "tmp" {
+  tags = { Name = "tmp" }
+}
+
+resource "aws_sqs_queue" "jobs" {}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	out, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+	want := `resource "aws_s3_bucket" "logs" {
+  bucket = "logs"
+  tags = {
+    Owner = var.owner
+    CostCenter = var.cost_center
+  }
+}
+
+resource "aws_s3_bucket" "data" {
+  tags = {
+    Name = "data"
+    Owner = var.owner
+    CostCenter = var.cost_center
+  }
+}
+
+resource "aws_s3_bucket" "tmp" {
+  tags = { Name = "tmp", Owner = var.owner, CostCenter = var.cost_center }
+}
+
+resource "aws_sqs_queue" "jobs" {
+  tags = {
+    Owner = var.owner
+    CostCenter = var.cost_center
+  }
+}
+`
+	// Team is not inserted: var.team is not declared
+	if string(out) != want {
+		t.Fatalf("unexpected result:\n%s", out)
+	}
+}
+
+func TestFixMarksSensitive(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables {
+  pattern               = ".*"
+  require_sensitive_for = "(password|token)"
+}
+outputs {
+  pattern               = ".*"
+  require_sensitive_for = "password"
+}
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `variable "db_password" {
+  type = string
+}
+
+variable "api_token" {
+  sensitive = false
+}
+
+variable "region" {}
+
+output "db_password" {
+  value = var.db_password
+}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	out, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+	want := `variable "db_password" {
+  type = string
+  sensitive = true
+}
+
+variable "api_token" {
+  sensitive = true
+}
+
+variable "region" {}
+
+output "db_password" {
+  value = var.db_password
+  sensitive = true
+}
+`
+	if string(out) != want {
+		t.Fatalf("unexpected result:\n%s", out)
+	}
+}
+
+func TestRemoveUnusedKeepsVariablesUsedByTagFixes(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+
+tags {
+  required       = ["Owner"]
+  types          = ["aws_*"]
+  from_variables = { Owner = "owner" }
+}
+
+unused {
+  variables = true
+}
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `variable "owner" {}
+
+variable "stale" {}
+
+resource "aws_sqs_queue" "jobs" {}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true, RemoveUnused: true}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	out, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+	if !strings.Contains(string(out), `variable "owner"`) || !strings.Contains(string(out), "Owner = var.owner") {
+		t.Fatalf("owner should be kept for the inserted tag:\n%s", out)
+	}
+	if strings.Contains(string(out), `variable "stale"`) {
+		t.Fatalf("stale should still be removed:\n%s", out)
+	}
+}
+
+func TestFixRemovesUnusedDeclarations(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules {
+  pattern          = ".*"
+  require_provider = false
+}
+resources { pattern = ".*" }
+
+unused {
+  variables = true
+  locals    = true
+  data      = true
+}
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `# region to deploy to
+variable "region" {}
+
+variable "name" {}
+
+locals {
+  prefix = "app"
+  full   = "${local.prefix}-x"
+  used   = var.name
+  id     = var.name
+}
+
+locals {
+  dead = 1
+}
+
+data "aws_caller_identity" "current" {}
+
+module "child" {
+  source = "./child"
+  size   = 1
+}
+
+output "name" {
+  value = [local.used, local.id]
+}
+
+data "aws_region" "current" {}
+`)
+	writeFile(t, filepath.Join(dir, "child", "main.tf"), `variable "size" {}
+
+variable "extra" {}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true}); err != nil {
+		t.Fatalf("fix without --remove-unused: %v", err)
+	}
+	if out, _ := os.ReadFile(filepath.Join(dir, "main.tf")); !strings.Contains(string(out), `variable "region"`) {
+		t.Fatalf("declarations removed without --remove-unused")
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true, RemoveUnused: true}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	out, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+	want := `variable "name" {}
+
+locals {
+  used = var.name
+  id   = var.name
+}
+
+module "child" {
+  source = "./child"
+  size   = 1
+}
+
+output "name" {
+  value = [local.used, local.id]
+}
+`
+	if string(out) != want {
+		t.Fatalf("unexpected result:\n%s", out)
+	}
+	// size is unused in the child but still passed by the caller
+	child, _ := os.ReadFile(filepath.Join(dir, "child", "main.tf"))
+	if string(child) != "variable \"size\" {}\n" {
+		t.Fatalf("unexpected child:\n%s", child)
+	}
+}
+
+func TestFixMovesBlocksToLayoutFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = "^[a-z_]+$" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+
+layout {
+  variable  = ["variables.tf"]
+  output    = ["outputs.tf"]
+  terraform = ["versions.tf"]
+}
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `terraform {
+  required_version = ">= 1.5"
+}
+
+# Region to deploy to
+variable "Region" {}
+
+resource "aws_s3_bucket" "logs" {
+  bucket = var.Region
+}
+
+output "bucket" {
+  value = aws_s3_bucket.logs.id
+} # bucket id
+`)
+	writeFile(t, filepath.Join(dir, "variables.tf"), `variable "name" {}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{DryRun: true, FixKinds: map[string]bool{"variable": true}}); err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outputs.tf")); !os.IsNotExist(err) {
+		t.Fatalf("layout applied without the layout fix type")
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+
+	want := map[string]string{
+		"main.tf": `resource "aws_s3_bucket" "logs" {
+  bucket = var.region
+}
+`,
+		"variables.tf": `variable "name" {}
+
+# Region to deploy to
+variable "region" {}
+`,
+		"outputs.tf": `output "bucket" {
+  value = aws_s3_bucket.logs.id
+} # bucket id
+`,
+		"versions.tf": `terraform {
+  required_version = ">= 1.5"
+}
+`,
+	}
+	for name, content := range want {
+		got, _ := os.ReadFile(filepath.Join(dir, name))
+		if string(got) != content {
+			t.Errorf("unexpected %s:\n%s", name, got)
+		}
+	}
+
+	if err := rewrite.Undo(dir); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	for _, name := range []string{"outputs.tf", "versions.tf"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Fatalf("%s created by the fix should be removed by undo", name)
+		}
+	}
+}
+
+func TestFixOrdersBlocksAndMetaArguments(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules {
+  pattern          = ".*"
+  require_provider = false
+}
+resources { pattern = ".*" }
+
+block_spacing { allow_compact = ["variable"] }
+
+ordering {
+  sort           = ["variable"]
+  meta_arguments = "last"
+}
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `# Availability zone
+variable "zone" {
+  type = string
+}
+variable "name" {}
+variable "app" {}
+
+resource "aws_instance" "web" {
+  count = 2
+  # the image
+  ami = "x"
+
+  lifecycle {
+    create_before_destroy = true
+  }
+  tags = {}
+}
+
+module "net" {
+  depends_on = [aws_instance.web]
+  source     = "./net"
+}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true, FixKinds: map[string]bool{"ordering": true, "spacing": true}}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+	want := `variable "app" {}
+variable "name" {}
+
+# Availability zone
+variable "zone" {
+  type = string
+}
+
+resource "aws_instance" "web" {
+  # the image
+  ami = "x"
+  tags = {}
+
+  count = 2
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+
+module "net" {
+  source     = "./net"
+  depends_on = [aws_instance.web]
+}
+`
+	if string(got) != want {
+		t.Fatalf("unexpected result:\n%s", got)
+	}
+	findings, err := parser.ParseFile(filepath.Join(dir, "main.tf"), cfg)
+	if err != nil || len(findings) != 0 {
+		t.Fatalf("findings after fix: %v %v", err, findings)
+	}
+}
+
+func TestFixMovesBlockCommentsWithTheirBlock(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "tfsuit.hcl"), `
+variables { pattern = ".*" }
+outputs   { pattern = ".*" }
+modules   { pattern = ".*" }
+resources { pattern = ".*" }
+
+ordering { sort = ["variable"] }
+`)
+	writeFile(t, filepath.Join(dir, "main.tf"), `/*
+  Availability zone
+*/
+variable "zone" {}
+/* the name */
+variable "name" {}
+`)
+	cfg, err := config.Load(filepath.Join(dir, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(dir, cfg, rewrite.Options{Write: true, FixKinds: map[string]bool{"ordering": true, "spacing": true}}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	got, _ := os.ReadFile(filepath.Join(dir, "main.tf"))
+	want := `/* the name */
+variable "name" {}
+
+/*
+  Availability zone
+*/
+variable "zone" {}
+`
+	if string(got) != want {
+		t.Fatalf("unexpected result:\n%s", got)
+	}
+}
+
+func TestFixWriteJournalAndUndo(t *testing.T) {
+	tmp := t.TempDir()
+	if err := copyDir(filepath.FromSlash("../../samples/simple"), tmp); err != nil {
+		t.Fatalf("copyDir: %v", err)
+	}
+	renamed := filepath.Join(tmp, "Bad-Name.tf")
+	writeFile(t, renamed, `variable "Other-Var" {}`)
+	writeFile(t, filepath.Join(tmp, "tfsuit.hcl"), `
+variables { pattern = "^[a-z0-9_]+$" }
+outputs   { pattern = "^[a-z0-9_]+$" }
+modules   { pattern = "^[a-z0-9_]+$" }
+resources { pattern = "^[a-z0-9_]+$" }
+files     { pattern = "^[a-z0-9_]+\\.tf$" }
+`)
+	cfg, err := config.Load(filepath.Join(tmp, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	badPath := filepath.Join(tmp, "bad.tf")
+	before, _ := os.ReadFile(badPath)
+
+	if err := rewrite.Undo(tmp); err == nil {
+		t.Fatalf("undo without journal should fail")
+	}
+	if err := rewrite.Run(tmp, cfg, rewrite.Options{Write: true}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, rewrite.JournalFile)); err != nil {
+		t.Fatalf("journal not written: %v", err)
+	}
+	after, _ := os.ReadFile(badPath)
+	if string(after) == string(before) {
+		t.Fatalf("fix did not change bad.tf")
+	}
+
+	if err := rewrite.Undo(tmp); err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	restored, _ := os.ReadFile(badPath)
+	if string(restored) != string(before) {
+		t.Fatalf("bad.tf not restored:\n%s", restored)
+	}
+	if _, err := os.Stat(renamed); err != nil {
+		t.Fatalf("file rename not reverted: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "bad_name.tf")); !os.IsNotExist(err) {
+		t.Fatalf("renamed file should be gone after undo")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, rewrite.JournalFile)); !os.IsNotExist(err) {
+		t.Fatalf("journal should be removed after undo")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, ".terraform")); !os.IsNotExist(err) {
+		t.Fatalf("empty .terraform should be removed after undo")
+	}
+}
+
+func TestUndoRefusesEditedFiles(t *testing.T) {
+	tmp := t.TempDir()
+	if err := copyDir(filepath.FromSlash("../../samples/simple"), tmp); err != nil {
+		t.Fatalf("copyDir: %v", err)
+	}
+	cfg, err := config.Load(filepath.Join(tmp, "tfsuit.hcl"))
+	if err != nil {
+		t.Fatalf("load cfg: %v", err)
+	}
+	if err := rewrite.Run(tmp, cfg, rewrite.Options{Write: true}); err != nil {
+		t.Fatalf("fix: %v", err)
+	}
+	badPath := filepath.Join(tmp, "bad.tf")
+	edited := "# edited by hand\n"
+	writeFile(t, badPath, edited)
+	if err := rewrite.Undo(tmp); err == nil || !strings.Contains(err.Error(), "changed after the fix") {
+		t.Fatalf("expected refusal, got %v", err)
+	}
+	out, _ := os.ReadFile(badPath)
+	if string(out) != edited {
+		t.Fatalf("undo must not overwrite later edits")
+	}
+}
+
 // utilidades -----------------------------------------------------------------
 
 func copyDir(src, dst string) error {
@@ -0,0 +1,142 @@
+package rewrite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/parser"
+)
+
+// tagInsertion adds missing required tags to a resource as var.<name> references.
+type tagInsertion struct {
+	Path    string
+	Address string
+	Keys    []string
+	Edit    textEdit
+}
+
+// planTagFixes schedules the missing tags that tags.from_variables maps to a variable declared
+// in the resource's module; the others are only reported.
+func planTagFixes(files []string, cfgs map[string]*config.Config, declared map[string]map[string]string) map[string][]tagInsertion {
+	_, issues := parser.CheckTags(files, cfgs)
+	out := map[string][]tagInsertion{}
+	for _, issue := range issues {
+		policy := cfgs[issue.File].Tags
+		dir := filepath.Dir(issue.File)
+		var items []string
+		var keys []string
+		for _, key := range issue.Missing {
+			name, ok := policy.FromVariables[key]
+			if !ok {
+				continue
+			}
+			if _, ok := declared[dir]["var."+name]; !ok {
+				fmt.Printf("note: %s: tag %s not added, variable %q is not declared in %s\n", issue.Address, key, name, dir)
+				continue
+			}
+			keys = append(keys, key)
+			items = append(items, fmt.Sprintf("%s = var.%s", tagKey(key), name))
+		}
+		if len(items) == 0 {
+			continue
+		}
+		src, err := ioutil.ReadFile(issue.File)
+		if err != nil {
+			continue
+		}
+		file, diags := hclsyntax.ParseConfig(src, issue.File, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			continue
+		}
+		block := findResource(file.Body.(*hclsyntax.Body), issue.Address)
+		if block == nil {
+			continue
+		}
+		edit, ok := tagEdit(src, block, policy.TagAttribute(), items)
+		if !ok {
+			fmt.Printf("note: %s: tags are not a literal object, add %s by hand\n", issue.Address, strings.Join(keys, ", "))
+			continue
+		}
+		out[issue.File] = append(out[issue.File], tagInsertion{Path: issue.File, Address: issue.Address, Keys: keys, Edit: edit})
+	}
+	for _, list := range out {
+		sort.Slice(list, func(i, j int) bool { return list[i].Edit.Start < list[j].Edit.Start })
+	}
+	return out
+}
+
+func findResource(body *hclsyntax.Body, address string) *hclsyntax.Block {
+	for _, b := range body.Blocks {
+		if b.Type == "resource" && len(b.Labels) == 2 && b.Labels[0]+"."+b.Labels[1] == address {
+			return b
+		}
+	}
+	return nil
+}
+
+// tagKey quotes keys that are not valid identifiers (aws:team, Cost Center).
+func tagKey(key string) string {
+	if labelIdentRe.MatchString(key) {
+		return key
+	}
+	return fmt.Sprintf("%q", key)
+}
+
+// tagEdit inserts items into the tags object of block, creating the attribute when missing.
+// With merge(), the last object argument receives them.
+func tagEdit(src []byte, block *hclsyntax.Block, attrName string, items []string) (textEdit, bool) {
+	attr, ok := block.Body.Attributes[attrName]
+	if !ok {
+		brace := block.CloseBraceRange.Start.Byte
+		indent := indentAt(src, brace)
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%s  %s = {\n", indent, attrName)
+		for _, it := range items {
+			fmt.Fprintf(&sb, "%s    %s\n", indent, it)
+		}
+		fmt.Fprintf(&sb, "%s  }\n", indent)
+		return insertBefore(src, brace, indent, sb.String()), true
+	}
+
+	var obj *hclsyntax.ObjectConsExpr
+	switch e := attr.Expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		obj = e
+	case *hclsyntax.FunctionCallExpr:
+		if e.Name != "merge" {
+			return textEdit{}, false
+		}
+		for _, arg := range e.Args {
+			if o, ok := arg.(*hclsyntax.ObjectConsExpr); ok {
+				obj = o
+			}
+		}
+	}
+	if obj == nil {
+		return textEdit{}, false
+	}
+
+	rng := obj.Range()
+	brace := rng.End.Byte - 1
+	if rng.Start.Line == rng.End.Line {
+		// objeto en una línea: se mantiene en una línea
+		if len(obj.Items) == 0 {
+			return textEdit{Start: brace, End: brace, Text: " " + strings.Join(items, ", ") + " "}, true
+		}
+		end := obj.Items[len(obj.Items)-1].ValueExpr.Range().End.Byte
+		return textEdit{Start: end, End: end, Text: ", " + strings.Join(items, ", ")}, true
+	}
+	indent := indentAt(src, brace)
+	var sb strings.Builder
+	for _, it := range items {
+		fmt.Fprintf(&sb, "%s  %s\n", indent, it)
+	}
+	return insertBefore(src, brace, indent, sb.String()), true
+}
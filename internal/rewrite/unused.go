@@ -0,0 +1,265 @@
+package rewrite
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	hcl "github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/modules"
+	"github.com/josdagaro/tfsuit/internal/parser"
+	"github.com/josdagaro/tfsuit/internal/refs"
+)
+
+// planRemovals deletes the declarations the unused checks report (fix --remove-unused). Usage is
+// taken from the sources with the added edits applied, so a variable a tags or provider fix starts
+// referencing is kept. Variables still passed by a module call are kept too, since removing them
+// would break the caller. realign lists, per file, the locals left in blocks that lost entries.
+func planRemovals(files []string, cfgs map[string]*config.Config, mods *modules.Resolver, added map[string][]textEdit) (out map[string][]textEdit, realign map[string][]string, count int, err error) {
+	ix := refs.New()
+	for _, path := range files {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		_ = ix.Add(path, applyEdits(src, append([]textEdit(nil), added[path]...)))
+	}
+	passed := map[string]map[string]bool{} // child dir → arguments set by its callers
+	for dir, m := range ix.Modules {
+		for call, source := range m.Calls {
+			child, ok := mods.Resolve(dir, call, source)
+			if !ok {
+				continue
+			}
+			if passed[child] == nil {
+				passed[child] = map[string]bool{}
+			}
+			for _, arg := range m.Args[call] {
+				passed[child][arg] = true
+			}
+		}
+	}
+
+	byFile := map[string][]refs.Decl{}
+	for dir, m := range ix.Modules {
+		for _, d := range parser.UnusedDecls(m, cfgs) {
+			name := parser.UnusedName(d)
+			if d.Kind == "variable" && passed[dir][name] {
+				fmt.Printf("note: variable %q in %s is unused but still passed by a module call; not removed\n", name, dir)
+				continue
+			}
+			byFile[d.File] = append(byFile[d.File], d)
+		}
+	}
+
+	out = map[string][]textEdit{}
+	realign = map[string][]string{}
+	for path, decls := range byFile {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			continue
+		}
+		dead := map[string]bool{}
+		for _, d := range decls {
+			dead[d.Address] = true
+		}
+		var ranges []hcl.Range
+		for _, b := range file.Body.(*hclsyntax.Body).Blocks {
+			if b.Type == "locals" {
+				var attrs []hcl.Range
+				var kept []string
+				for name, attr := range b.Body.Attributes {
+					if dead["local."+name] {
+						attrs = append(attrs, attr.SrcRange)
+					} else {
+						kept = append(kept, name)
+					}
+				}
+				if len(attrs) > 0 && len(attrs) == len(b.Body.Attributes) && len(b.Body.Blocks) == 0 {
+					ranges = append(ranges, b.Range()) // sin atributos, se elimina el bloque entero
+				} else {
+					ranges = append(ranges, attrs...)
+					if len(attrs) > 0 {
+						realign[path] = append(realign[path], kept...)
+					}
+				}
+				count += len(attrs)
+				continue
+			}
+			if addr := addressOfBlock(b); dead[addr] && (b.Type == "variable" || b.Type == "data") {
+				ranges = append(ranges, b.Range())
+				count++
+			}
+		}
+		out[path] = deletions(src, ranges)
+	}
+	return out, realign, count, nil
+}
+
+// alignLocals re-aligns the = of the locals blocks declaring any of names the way terraform fmt
+// does: across consecutive one-line attributes, one space after the longest name.
+func alignLocals(path string, src []byte, names []string) []byte {
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return src
+	}
+	want := map[string]bool{}
+	for _, n := range names {
+		want[n] = true
+	}
+	var edits []textEdit
+	for _, b := range file.Body.(*hclsyntax.Body).Blocks {
+		if b.Type != "locals" {
+			continue
+		}
+		var attrs []*hclsyntax.Attribute
+		touched := false
+		for name, attr := range b.Body.Attributes {
+			attrs = append(attrs, attr)
+			touched = touched || want[name]
+		}
+		if !touched {
+			continue
+		}
+		sort.Slice(attrs, func(i, j int) bool { return attrs[i].SrcRange.Start.Byte < attrs[j].SrcRange.Start.Byte })
+		for start := 0; start < len(attrs); {
+			end := start + 1
+			for end < len(attrs) {
+				prev := attrs[end-1].SrcRange
+				if prev.End.Line != prev.Start.Line || attrs[end].SrcRange.Start.Line != prev.End.Line+1 {
+					break
+				}
+				end++
+			}
+			width := 0
+			for _, a := range attrs[start:end] {
+				if n := a.NameRange.End.Column - a.NameRange.Start.Column; n > width {
+					width = n
+				}
+			}
+			for _, a := range attrs[start:end] {
+				pad := width - (a.NameRange.End.Column - a.NameRange.Start.Column) + 1
+				edits = append(edits, textEdit{Start: a.NameRange.End.Byte, End: a.EqualsRange.Start.Byte, Text: strings.Repeat(" ", pad)})
+			}
+			start = end
+		}
+	}
+	return applyEdits(src, edits)
+}
+
+// insertionEdits gathers the provider, sensitive and tags insertions planned for each file.
+func insertionEdits(providers map[string][]providerInsertion, sensitive map[string][]textEdit, tags map[string][]tagInsertion) map[string][]textEdit {
+	out := map[string][]textEdit{}
+	for path, ins := range providers {
+		for _, fix := range ins {
+			out[path] = append(out[path], textEdit{Start: fix.Offset, End: fix.Offset, Text: fix.payload()})
+		}
+	}
+	for path, edits := range sensitive {
+		out[path] = append(out[path], edits...)
+	}
+	for path, ins := range tags {
+		for _, tf := range ins {
+			out[path] = append(out[path], tf.Edit)
+		}
+	}
+	return out
+}
+
+// deletions turns ranges into line deletions that also take the comments right above each range
+// and the blank lines after it (before it at the end of the file), merging the ones that touch.
+func deletions(src []byte, ranges []hcl.Range) []textEdit {
+	var edits []textEdit
+	comments := parser.CommentLines(src)
+	for _, rng := range ranges {
+		start := leadingComments(src, comments, rng.Start.Byte)
+		end := rng.End.Byte
+		for end < len(src) && src[end] != '\n' {
+			end++
+		}
+		if end < len(src) {
+			end++
+		}
+		for end < len(src) {
+			next := end
+			for next < len(src) && src[next] != '\n' {
+				next++
+			}
+			if strings.TrimSpace(string(src[end:next])) != "" {
+				break
+			}
+			if next < len(src) {
+				next++
+			}
+			end = next
+		}
+		if end == len(src) {
+			for start > 0 {
+				prev := lineStartOf(src, start-1)
+				if strings.TrimSpace(string(src[prev:start])) != "" {
+					break
+				}
+				start = prev
+			}
+		}
+		edits = append(edits, textEdit{Start: start, End: end})
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].Start < edits[j].Start })
+	var merged []textEdit
+	for _, e := range edits {
+		if n := len(merged); n > 0 && e.Start <= merged[n-1].End {
+			if e.End > merged[n-1].End {
+				merged[n-1].End = e.End
+			}
+			continue
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// leadingComments returns the start of the line at offset, moved up over the comment lines
+// right above it (see parser.CommentLines).
+func leadingComments(src []byte, comments map[int]bool, offset int) int {
+	start := lineStartOf(src, offset)
+	line := bytes.Count(src[:start], []byte("\n")) + 1
+	for n := parser.CommentStart(comments, line, 0); n < line; n++ {
+		start = lineStartOf(src, start-1)
+	}
+	return start
+}
+
+func lineStartOf(src []byte, offset int) int {
+	for offset > 0 && src[offset-1] != '\n' {
+		offset--
+	}
+	return offset
+}
+
+// dropInside discards the edits that fall in text being deleted, such as renames of references
+// inside a removed block.
+func dropInside(edits, dels []textEdit) []textEdit {
+	var out []textEdit
+	for _, e := range edits {
+		inside := false
+		for _, d := range dels {
+			if e.Start >= d.Start && e.End <= d.End && e.Start < d.End {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			out = append(out, e)
+		}
+	}
+	return out
+}
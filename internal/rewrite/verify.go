@@ -0,0 +1,111 @@
+package rewrite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/josdagaro/tfsuit/internal/config"
+	"github.com/josdagaro/tfsuit/internal/engine"
+	"github.com/josdagaro/tfsuit/internal/modules"
+	"github.com/josdagaro/tfsuit/internal/refs"
+)
+
+// checkReferences indexes the tree before and after the staged edits and fails if the fix would
+// leave var., local., module. or resource references pointing at names that no longer exist.
+// References that were already dangling before the fix are not reported.
+func checkReferences(mods *modules.Resolver, files []string, staged []stagedFile) error {
+	root := mods.Root
+	content := make(map[string][]byte, len(staged))
+	for _, sf := range staged {
+		content[sf.Path] = sf.Content
+	}
+	before, after := refs.New(), refs.New()
+	before.ResolveChild, after.ResolveChild = mods.Resolve, mods.Resolve
+	for _, path := range files {
+		orig, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		_ = before.Add(path, orig)
+		if c, ok := content[path]; ok {
+			orig = c
+		}
+		_ = after.Add(path, orig)
+		delete(content, path)
+	}
+	// archivos nuevos (bloques movidos por el layout)
+	for path, c := range content {
+		_ = after.Add(path, c)
+	}
+	// módulos remotos (.terraform/modules, vendor): se indexan tal cual para conocer sus outputs
+	for _, dir := range remoteChildren(before) {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.tf"))
+		for _, path := range matches {
+			if src, err := ioutil.ReadFile(path); err == nil {
+				_ = before.Add(path, src)
+				_ = after.Add(path, src)
+			}
+		}
+	}
+
+	known := map[string]int{}
+	for _, ref := range before.Dangling() {
+		known[danglingKey(ref)]++
+	}
+	var broken []string
+	for _, ref := range after.Dangling() {
+		key := danglingKey(ref)
+		if known[key] > 0 {
+			known[key]--
+			continue
+		}
+		broken = append(broken, fmt.Sprintf("%s:%d: %s", relPath(root, ref.File), ref.Line, ref.Target()))
+	}
+	if len(broken) > 0 {
+		return fmt.Errorf("fix aborted, it would leave dangling references:\n  %s", strings.Join(broken, "\n  "))
+	}
+	return nil
+}
+
+// remoteChildren lists the resolvable module calls whose directory is not indexed yet.
+func remoteChildren(ix *refs.Index) []string {
+	seen := map[string]bool{}
+	var out []string
+	for dir, m := range ix.Modules {
+		for call, source := range m.Calls {
+			child, ok := ix.ResolveChild(dir, call, source)
+			if !ok || seen[child] {
+				continue
+			}
+			seen[child] = true
+			if _, indexed := ix.Modules[child]; !indexed {
+				out = append(out, child)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// danglingKey identifies a reference by module rather than file, since fix may move blocks.
+func danglingKey(ref refs.Ref) string {
+	return filepath.Dir(ref.File) + "\x00" + ref.Address + "\x00" + ref.Output
+}
+
+// rescan runs engine.Scan over the written tree, the same scan `tfsuit scan` runs, and reports
+// how many violations are still flagged. It only reports: the files are already written, and
+// cross-file checks (tags, unused declarations, moved targets, module calls) need the whole tree
+// on disk.
+func rescan(root string, cfg *config.Config) (int, error) {
+	findings, _, err := engine.Scan(root, cfg)
+	if err != nil {
+		return 0, fmt.Errorf("post-fix scan failed: %w", err)
+	}
+	if len(findings) > 0 {
+		fmt.Printf("note: %d violations remain after the fix (see tfsuit scan)\n", len(findings))
+	}
+	return len(findings), nil
+}
@@ -19,3 +19,8 @@ data {
   pattern = "^[a-z0-9_]+$"
   require_provider = true
 }
+
+provider_chain {
+  mappings   = true
+  references = true
+}